@@ -0,0 +1,90 @@
+/*
+Bulk interface generation from a CIDR plan
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// AllocateRequest is the body of /api/v1/allocate: Count interfaces named
+// by NamePattern, with "{n}" replaced by 0, 1, ..., get consecutive
+// addresses starting at the address of CIDR
+type AllocateRequest struct {
+	CIDR        string `json:"cidr"`
+	Count       int    `json:"count"`
+	NamePattern string `json:"namePattern"`
+	Renderer    string `json:"renderer"`
+}
+
+// handleAllocate converts a CIDR plan into form data and returns both the
+// form data and the config generated from it
+func handleAllocate(w http.ResponseWriter, r *http.Request) {
+	var request AllocateRequest
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+
+	formData, err := allocateInterfaces(request)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	formData.Renderer = request.Renderer
+	writeConvertedFormData(w, r, formData)
+}
+
+// allocateInterfaces builds static ethernets with sequential addresses. If
+// the CIDR gives the subnet's network address, allocation starts at the
+// address after it. For IPv4 subnets with room for a broadcast address,
+// that address is never allocated.
+func allocateInterfaces(request AllocateRequest) (FormData, error) {
+	var formData FormData
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(request.CIDR))
+	if err != nil {
+		return formData, fmt.Errorf("invalid CIDR %q: %v", request.CIDR, err)
+	}
+	if request.Count <= 0 || request.Count > maxInterfaces {
+		return formData, fmt.Errorf("invalid count %d (expected 1-%d)", request.Count, maxInterfaces)
+	}
+	pattern := request.NamePattern
+	if pattern == "" {
+		pattern = "eth{n}"
+	}
+	if request.Count > 1 && !strings.Contains(pattern, "{n}") {
+		return formData, fmt.Errorf("name pattern %q needs {n} to give %d interfaces different names", pattern, request.Count)
+	}
+
+	addr := prefix.Addr()
+	subnet := prefix.Masked()
+	hostBits := addr.BitLen() - prefix.Bits()
+	if addr == subnet.Addr() && hostBits > 1 {
+		addr = addr.Next()
+	}
+	for i := 0; i < request.Count; i++ {
+		broadcast := addr.Is4() && hostBits > 1 && !subnet.Contains(addr.Next())
+		if !addr.IsValid() || !subnet.Contains(addr) || broadcast {
+			return formData, fmt.Errorf("subnet %s has room for only %d addresses from %s, %d requested", subnet, i, prefix.Addr(), request.Count)
+		}
+		formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{
+			Type:      "ethernet",
+			Name:      strings.ReplaceAll(pattern, "{n}", strconv.Itoa(i)),
+			UseStatic: true,
+			Addresses: netip.PrefixFrom(addr, prefix.Bits()).String(),
+		})
+		addr = addr.Next()
+	}
+	return formData, nil
+}
@@ -0,0 +1,83 @@
+/*
+Tests for bulk interface generation from a CIDR plan
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAllocateInterfaces(t *testing.T) {
+	formData, err := allocateInterfaces(AllocateRequest{CIDR: "10.0.0.2/24", Count: 3, NamePattern: "ens{n}"})
+	if err != nil {
+		t.Fatalf("allocateInterfaces failed: %v", err)
+	}
+	expected := [][2]string{{"ens0", "10.0.0.2/24"}, {"ens1", "10.0.0.3/24"}, {"ens2", "10.0.0.4/24"}}
+	if len(formData.Interfaces) != len(expected) {
+		t.Fatalf("Expected %d interfaces, got %+v", len(expected), formData.Interfaces)
+	}
+	for i, iface := range formData.Interfaces {
+		if iface.Name != expected[i][0] || iface.Addresses != expected[i][1] || !iface.UseStatic {
+			t.Errorf("Interface %d = %+v, want %s with %s", i, iface, expected[i][0], expected[i][1])
+		}
+	}
+
+	// The network address is skipped
+	formData, err = allocateInterfaces(AllocateRequest{CIDR: "192.168.5.0/30", Count: 2})
+	if err != nil {
+		t.Fatalf("allocateInterfaces failed: %v", err)
+	}
+	if formData.Interfaces[0].Addresses != "192.168.5.1/30" || formData.Interfaces[1].Name != "eth1" {
+		t.Errorf("Unexpected interfaces %+v", formData.Interfaces)
+	}
+
+	tests := []struct {
+		request AllocateRequest
+		err     string
+	}{
+		{AllocateRequest{CIDR: "192.168.5.0/30", Count: 3}, "room for only 2 addresses"},
+		{AllocateRequest{CIDR: "10.0.0.250/24", Count: 6}, "room for only 5 addresses"},
+		{AllocateRequest{CIDR: "10.0.0.0/24", Count: 2, NamePattern: "eth0"}, "needs {n}"},
+		{AllocateRequest{CIDR: "10.0.0.0", Count: 1}, "invalid CIDR"},
+		{AllocateRequest{CIDR: "10.0.0.0/24", Count: 0}, "invalid count"},
+	}
+	for _, test := range tests {
+		if _, err := allocateInterfaces(test.request); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%+v: expected an error containing %q, got %v", test.request, test.err, err)
+		}
+	}
+}
+
+func TestHandleAllocate(t *testing.T) {
+	body := `{"cidr": "2001:db8::10/64", "count": 2, "namePattern": "eth{n}"}`
+	req := httptest.NewRequest("POST", "/api/v1/allocate", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleAllocate(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		YAML string `json:"yaml"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	for _, want := range []string{"- 2001:db8::10/64", "- 2001:db8::11/64"} {
+		if !strings.Contains(response.YAML, want) {
+			t.Errorf("Expected %q in:\n%s", want, response.YAML)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Preview of the files netplan generates for a config
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// netplanPath is the netplan binary found at startup; /api/v1/apply-preview
+// is only registered when there is one
+var netplanPath string
+
+// allowApply permits /api/v1/apply-preview to run netplan on this host; it
+// is set from ALLOW_APPLY
+var allowApply bool
+
+// liveRoot is the root of the system the generated files are compared with
+var liveRoot = "/"
+
+// netplanGenerateTimeout bounds a single netplan generate run
+const netplanGenerateTimeout = 10 * time.Second
+
+// GeneratedFile is a file netplan generate would write, with Status
+// "created" if it doesn't exist on the system yet, or "changed" or
+// "unchanged" compared with the one that does
+type GeneratedFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// handleApplyPreview generates the config for the posted FormData, runs
+// netplan generate on it in a temporary root and lists the files it writes
+func handleApplyPreview(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+	if !allowApply {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "running netplan is disabled; set ALLOW_APPLY to enable it"})
+		return
+	}
+	if netplanPath == "" {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "netplan is not installed"})
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	files, err := applyPreview(r.Context(), renderYAML(config, formData))
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"files": files})
+}
+
+// applyPreview writes yaml into /etc/netplan of a temporary root, runs
+// netplan generate --root-dir on it and compares every file it writes
+// with the same path under liveRoot
+func applyPreview(ctx context.Context, yaml string) ([]GeneratedFile, error) {
+	root, err := os.MkdirTemp("", "netplan-preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+
+	netplanDir := filepath.Join(root, "etc", "netplan")
+	if err := os.MkdirAll(netplanDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(netplanDir, bundleYAMLName), []byte(yaml), 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, netplanGenerateTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, netplanPath, "generate", "--root-dir", root)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("netplan generate failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var files []GeneratedFile
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == netplanDir {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		generated, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		status := "unchanged"
+		live, err := os.ReadFile(filepath.Join(liveRoot, rel))
+		switch {
+		case os.IsNotExist(err):
+			status = "created"
+		case err != nil:
+			return err
+		case !bytes.Equal(live, generated):
+			status = "changed"
+		}
+		files = append(files, GeneratedFile{Path: "/" + filepath.ToSlash(rel), Status: status})
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,92 @@
+/*
+Tests for previews of the files netplan generates
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeNetplan stands in for netplan generate: it checks the config was
+// written into the root and generates one file per status
+const fakeNetplan = `#!/bin/sh
+[ "$1" = generate ] && [ "$2" = --root-dir ] || exit 2
+grep -q 192.168.1.10/24 "$3/etc/netplan/90-netplan-web.yaml" || exit 3
+mkdir -p "$3/run/systemd/network"
+echo new > "$3/run/systemd/network/10-netplan-eth0.network"
+echo new > "$3/run/systemd/network/10-netplan-eth1.network"
+echo same > "$3/run/systemd/network/10-netplan-eth2.network"
+`
+
+func TestApplyPreview(t *testing.T) {
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "netplan")
+	if err := os.WriteFile(fake, []byte(fakeNetplan), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	live := filepath.Join(dir, "live")
+	networkDir := filepath.Join(live, "run", "systemd", "network")
+	if err := os.MkdirAll(networkDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(networkDir, "10-netplan-eth1.network"), []byte("old\n"), 0o644)
+	os.WriteFile(filepath.Join(networkDir, "10-netplan-eth2.network"), []byte("same\n"), 0o644)
+
+	defer func(path, root string, allow bool) { netplanPath, liveRoot, allowApply = path, root, allow }(netplanPath, liveRoot, allowApply)
+	netplanPath, liveRoot, allowApply = fake, live, true
+
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0","useStatic":true,"addresses":"192.168.1.10/24"}],"renderer":"networkd"}`
+	req := httptest.NewRequest("POST", "/api/v1/apply-preview", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleApplyPreview(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Files []GeneratedFile `json:"files"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	expected := []GeneratedFile{
+		{Path: "/run/systemd/network/10-netplan-eth0.network", Status: "created"},
+		{Path: "/run/systemd/network/10-netplan-eth1.network", Status: "changed"},
+		{Path: "/run/systemd/network/10-netplan-eth2.network", Status: "unchanged"},
+	}
+	if !reflect.DeepEqual(response.Files, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, response.Files)
+	}
+
+	// Running netplan on the host has to be allowed with ALLOW_APPLY
+	allowApply = false
+	w = httptest.NewRecorder()
+	handleApplyPreview(w, httptest.NewRequest("POST", "/api/v1/apply-preview", strings.NewReader(body)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without ALLOW_APPLY, got %d", w.Code)
+	}
+	allowApply = true
+
+	// Without netplan there is nothing to preview with
+	netplanPath = ""
+	w = httptest.NewRecorder()
+	handleApplyPreview(w, httptest.NewRequest("POST", "/api/v1/apply-preview", strings.NewReader(body)))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without netplan, got %d", w.Code)
+	}
+}
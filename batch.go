@@ -0,0 +1,79 @@
+/*
+Batch generation of many configs in one request
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+)
+
+// genConcurrency is the number of configs a batch generates at once; it is
+// set from GEN_CONCURRENCY
+var genConcurrency = runtime.NumCPU()
+
+// BatchResult is the outcome for one config of a batch: its YAML and
+// warnings, or the error that stopped its generation
+type BatchResult struct {
+	YAML     string   `json:"yaml,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// runPool calls fn with every index below count, from at most workers
+// goroutines at a time, and returns once all calls have returned
+func runPool(workers, count int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// generateBatch generates every config with the given number of workers;
+// the results are in the order of the configs, and an invalid config only
+// fails its own result
+func generateBatch(configs []FormData, workers int) []BatchResult {
+	results := make([]BatchResult, len(configs))
+	runPool(workers, len(configs), func(i int) {
+		config, err := generateNetplanConfig(configs[i])
+		if err != nil {
+			results[i] = BatchResult{Error: err.Error()}
+			return
+		}
+		results[i] = BatchResult{YAML: renderYAML(config, configs[i]), Warnings: config.Warnings}
+	})
+	return results
+}
+
+// handleBatch generates each of the posted configs, e.g. one per host of a
+// fleet, with GEN_CONCURRENCY workers
+func handleBatch(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Configs []FormData `json:"configs"`
+	}
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": generateBatch(request.Configs, genConcurrency)})
+}
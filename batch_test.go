@@ -0,0 +1,81 @@
+/*
+Tests for batch generation
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunPoolBoundsWorkers(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("GEN_CONCURRENCY", "3")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	active, peak, calls := 0, 0, 0
+	runPool(config.GenConcurrency, 20, func(i int) {
+		mu.Lock()
+		active++
+		calls++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+	})
+	if calls != 20 {
+		t.Errorf("Expected 20 calls, got %d", calls)
+	}
+	if peak > 3 {
+		t.Errorf("Expected at most 3 concurrent workers, got %d", peak)
+	}
+}
+
+func TestHandleBatch(t *testing.T) {
+	body := `{"configs":[` +
+		`{"interfaces":[{"type":"ethernet","name":"eth0"}],"renderer":"networkd"},` +
+		`{"interfaces":[]}]}`
+	req := httptest.NewRequest("POST", "/api/v1/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Results []BatchResult `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(response.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", response.Results)
+	}
+	if !strings.Contains(response.Results[0].YAML, "eth0:\n      dhcp4: true") || response.Results[0].Error != "" {
+		t.Errorf("Expected the first config to be generated, got %+v", response.Results[0])
+	}
+	if response.Results[1].Error != "at least one interface is required" || response.Results[1].YAML != "" {
+		t.Errorf("Expected the second config to fail, got %+v", response.Results[1])
+	}
+}
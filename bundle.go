@@ -0,0 +1,120 @@
+/*
+Download bundles of a generated config and an apply script
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// bundleYAMLName is the name of the config in a bundle, and the name the
+// apply script installs it under in /etc/netplan
+const bundleYAMLName = "90-netplan-web.yaml"
+
+// bundleScriptName is the name of the apply script in a bundle
+const bundleScriptName = "apply-netplan.sh"
+
+// bundleScript is a suggested way to install the config. It only prints
+// what it would do unless run with --apply, so it can't change the network
+// configuration by accident.
+var bundleScript = `#!/bin/sh
+# SUGGESTION ONLY: this script was generated along with ` + bundleYAMLName + `
+# as an example of how to install it. Review it before running it, and adapt
+# it to your system; it is not maintained or tested against your setup.
+#
+# It backs up /etc/netplan, copies ` + bundleYAMLName + ` into it and runs
+# "netplan try", which rolls back unless the new config is confirmed.
+# Nothing is changed unless it is run as root with --apply.
+set -eu
+
+backup="/etc/netplan.bak-$(date +%Y%m%d%H%M%S)"
+
+if [ "${1:-}" != "--apply" ]; then
+	echo "This would:"
+	echo "  1. back up /etc/netplan to $backup"
+	echo "  2. install ` + bundleYAMLName + ` as /etc/netplan/` + bundleYAMLName + `"
+	echo "  3. run netplan try"
+	echo "Review this script, then run it again with --apply to do so."
+	exit 0
+fi
+
+if [ "$(id -u)" -ne 0 ]; then
+	echo "Run with --apply as root." >&2
+	exit 1
+fi
+
+cd "$(dirname "$0")"
+cp -a /etc/netplan "$backup"
+echo "Backed up /etc/netplan to $backup"
+install -m 600 ` + bundleYAMLName + ` /etc/netplan/` + bundleYAMLName + `
+netplan try
+`
+
+// handleDownloadBundle generates the config for the posted FormData and
+// returns it as a tar archive together with bundleScript
+func handleDownloadBundle(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	bundle, err := buildBundle(renderYAML(config, formData), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to build bundle: " + err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="netplan-bundle.tar"`)
+	w.Write(bundle)
+}
+
+// buildBundle returns a tar archive holding yaml as bundleYAMLName and
+// the apply script as bundleScriptName
+func buildBundle(yaml string, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		name    string
+		mode    int64
+		content string
+	}{
+		{bundleYAMLName, 0o600, yaml},
+		{bundleScriptName, 0o755, bundleScript},
+	}
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    entry.mode,
+			Size:    int64(len(entry.content)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.name, err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+/*
+Tests for download bundles
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleDownloadBundle(t *testing.T) {
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0","useStatic":true,"addresses":"192.168.1.10/24"}],"renderer":"networkd"}`
+	req := httptest.NewRequest("POST", "/download/bundle", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleDownloadBundle(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-tar" {
+		t.Errorf("Expected a tar content type, got %q", contentType)
+	}
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(w.Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", header.Name, err)
+		}
+		entries[header.Name] = string(content)
+	}
+
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(entries))
+	}
+	if yaml, ok := entries[bundleYAMLName]; !ok || !strings.Contains(yaml, "- 192.168.1.10/24") {
+		t.Errorf("Expected the generated config as %s, got %q", bundleYAMLName, yaml)
+	}
+	script, ok := entries[bundleScriptName]
+	if !ok {
+		t.Fatalf("Expected the apply script as %s", bundleScriptName)
+	}
+	for _, want := range []string{"SUGGESTION ONLY", "--apply", "netplan try"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected %q in the apply script:\n%s", want, script)
+		}
+	}
+}
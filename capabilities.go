@@ -0,0 +1,59 @@
+/*
+Capabilities of this build for API clients
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Capabilities describes what generation supports in this build and
+// server configuration, so clients don't have to guess from the version
+type Capabilities struct {
+	// InterfaceTypes are the types generation accepts: the registered
+	// type handlers, limited to ALLOWED_TYPES if it is set
+	InterfaceTypes []string `json:"interfaceTypes"`
+	Renderers      []string `json:"renderers"`
+	TargetReleases []string `json:"targetReleases"`
+	BondModes      []string `json:"bondModes"`
+	Profiles       []string `json:"profiles"`
+	// ApplyPreview is whether /api/v1/apply-preview is available, which
+	// requires netplan on the server and ALLOW_APPLY
+	ApplyPreview bool `json:"applyPreview"`
+	// SavedConfigs is whether generated configs are saved, with SAVE_DIR
+	SavedConfigs bool `json:"savedConfigs"`
+}
+
+// currentCapabilities derives the capabilities from the registries and
+// settings that generation itself uses
+func currentCapabilities() Capabilities {
+	var types []string
+	for _, interfaceType := range sortedKeys(typeHandlers) {
+		if len(allowedTypes) == 0 || slices.Contains(allowedTypes, interfaceType) {
+			types = append(types, interfaceType)
+		}
+	}
+	return Capabilities{
+		InterfaceTypes: types,
+		Renderers:      []string{"networkd", "NetworkManager"},
+		TargetReleases: sortedKeys(releaseCapabilities),
+		BondModes:      bondModes,
+		Profiles:       sortedKeys(profiles),
+		ApplyPreview:   allowApply && netplanPath != "",
+		SavedConfigs:   savedConfigs != nil,
+	}
+}
+
+// handleCapabilities lists the capabilities as JSON
+func handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentCapabilities())
+}
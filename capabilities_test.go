@@ -0,0 +1,48 @@
+/*
+Tests for the capabilities endpoint
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHandleCapabilities(t *testing.T) {
+	capabilities := func() Capabilities {
+		w := httptest.NewRecorder()
+		handleCapabilities(w, httptest.NewRequest("GET", "/api/v1/capabilities", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response Capabilities
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		return response
+	}
+
+	types := capabilities().InterfaceTypes
+	for _, expected := range []string{"ethernet", "bond", "bridge"} {
+		if !slices.Contains(types, expected) {
+			t.Errorf("Expected %s in the interface types, got %v", expected, types)
+		}
+	}
+
+	defer func(types []string) { allowedTypes = types }(allowedTypes)
+	allowedTypes = []string{"ethernet"}
+	if types := capabilities().InterfaceTypes; !slices.Equal(types, []string{"ethernet"}) {
+		t.Errorf("Expected only the allowed ethernet type, got %v", types)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Detection of configs that wouldn't change the current one
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+)
+
+// ChangedRequest is the body of /api/v1/changed: Current is the netplan
+// YAML in place, FormData the config that would replace it
+type ChangedRequest struct {
+	Current  string   `json:"current"`
+	FormData FormData `json:"formData"`
+}
+
+// handleChanged reports whether the config generated from the posted
+// FormData differs from the current one, so automation can skip applying
+// a config that changes nothing
+func handleChanged(w http.ResponseWriter, r *http.Request) {
+	var request ChangedRequest
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+
+	current, err := parseNetplanYAML(request.Current)
+	if err != nil {
+		writeValidationError(w, r, fmt.Errorf("invalid current config: %v", err))
+		return
+	}
+	config, err := generateNetplanConfig(request.FormData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"changed": canonicalYAML(current) != canonicalYAML(config)})
+}
+
+// canonicalYAML writes config in a form that only differs between configs
+// netplan would apply differently: keys are always in the same order,
+// descriptions and disabled interfaces aren't written, settings left at
+// netplan's default are left out, a missing renderer is networkd, and
+// default gateways are written as default routes, in a sorted route list
+func canonicalYAML(config *NetplanConfig) string {
+	canonical := *config
+	canonical.Descriptions = nil
+	canonical.Disabled = nil
+	canonical.Warnings = nil
+	canonical.IndentWidth = 0
+	canonical.MinimalOutput = true
+	canonical.Explicit = false
+
+	// The interfaces are normalized in place, so they are copied first
+	network := &canonical.Network
+	network.Ethernets = maps.Clone(network.Ethernets)
+	network.Bonds = maps.Clone(network.Bonds)
+	network.Bridges = maps.Clone(network.Bridges)
+	network.VLANs = maps.Clone(network.VLANs)
+	network.DummyDevices = maps.Clone(network.DummyDevices)
+	network.Modems = maps.Clone(network.Modems)
+	network.NMDevices = maps.Clone(network.NMDevices)
+
+	if network.Renderer == "" {
+		network.Renderer = "networkd"
+	}
+	canonical.forEachInterface(func(name string, s *interfaceSettings) {
+		routes := make([]Route, 0, len(s.Routes))
+		for _, route := range s.Routes {
+			if route.To == "0.0.0.0/0" || route.To == "::/0" {
+				route.To = "default"
+			}
+			routes = append(routes, route)
+		}
+		s.Routes = routes
+	})
+	// Converting the gateway keys like for a release that deprecates them
+	// only fails for an interface with a gateway and a default route of
+	// the same family, which is then compared as it is written
+	_ = normalizeGateways(&canonical, "24.04")
+	canonical.forEachInterface(func(name string, s *interfaceSettings) {
+		sort.SliceStable(s.Routes, func(i, j int) bool {
+			return fmt.Sprint(s.Routes[i]) < fmt.Sprint(s.Routes[j])
+		})
+		if len(s.Routes) == 0 {
+			s.Routes = nil
+		}
+	})
+	return configToYAML(&canonical)
+}
@@ -0,0 +1,105 @@
+/*
+Tests for detecting configs that wouldn't change the current one
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleChanged(t *testing.T) {
+	// The same config as the form data below, with its keys, interfaces
+	// and comments in a different order
+	current := `# Deployed by hand
+network:
+  ethernets:
+    eth1:
+      dhcp4: true
+    eth0:
+      addresses:
+        - 192.168.1.10/24
+      # default gateway
+      gateway4: 192.168.1.1
+  renderer: networkd
+  version: 2
+`
+	formData := `{"interfaces":[` +
+		`{"type":"ethernet","name":"eth0","description":"uplink","useStatic":true,"addresses":"192.168.1.10/24","gateway4":"192.168.1.1"},` +
+		`{"type":"ethernet","name":"eth1","dhcp4":true}],"renderer":"networkd"}`
+
+	changed := func(current, formData string) bool {
+		body, _ := json.Marshal(map[string]interface{}{"current": current, "formData": json.RawMessage(formData)})
+		req := httptest.NewRequest("POST", "/api/v1/changed", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		handleChanged(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Changed bool `json:"changed"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		return response.Changed
+	}
+
+	if changed(current, formData) {
+		t.Error("Expected a reordered identical config to be unchanged")
+	}
+	if !changed(strings.Replace(current, "192.168.1.10/24", "192.168.1.11/24", 1), formData) {
+		t.Error("Expected a different address to be a change")
+	}
+}
+
+func TestCanonicalYAMLNormalizesDefaults(t *testing.T) {
+	generated, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1", Routes: "10.0.0.0/8 192.168.1.254"},
+		},
+		Renderer: "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	// No renderer means networkd, and a default route is what gateway4
+	// stands for, whether it is written to default or to 0.0.0.0/0
+	for _, to := range []string{"default", "0.0.0.0/0"} {
+		current, err := parseNetplanYAML(`network:
+  version: 2
+  ethernets:
+    eth0:
+      dhcp4: false
+      addresses:
+        - 192.168.1.10/24
+      routes:
+        - to: ` + to + `
+          via: 192.168.1.1
+        - to: 10.0.0.0/8
+          via: 192.168.1.254
+`)
+		if err != nil {
+			t.Fatalf("parseNetplanYAML failed: %v", err)
+		}
+		if got, want := canonicalYAML(current), canonicalYAML(generated); got != want {
+			t.Errorf("Expected a default route to %s to match gateway4:\n%s\nGot:\n%s", to, want, got)
+		}
+	}
+
+	if generated.Network.Ethernets["eth0"].Gateway4 != "192.168.1.1" || len(generated.Warnings) != 0 {
+		t.Errorf("Expected canonicalYAML to leave the config alone, got %+v", generated.Network.Ethernets["eth0"])
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Command line generation from a JSON form data file
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+)
+
+// watchInterval is how often -watch checks the input file for changes
+const watchInterval = time.Second
+
+// generateFile generates the config for the FormData in the JSON file at
+// input and writes it to output, or to stdout if output is empty
+func generateFile(input, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	var formData FormData
+	if err := json.Unmarshal(data, &formData); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %v", input, err)
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		return err
+	}
+	for _, warning := range config.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	yaml := renderYAML(config, formData)
+	if output == "" {
+		_, err = os.Stdout.WriteString(yaml)
+		return err
+	}
+	// netplan warns about config files readable by other users
+	return os.WriteFile(output, []byte(yaml), 0o600)
+}
+
+// inputWatcher reports changes to a file's modification time; stat is
+// os.Stat outside of tests
+type inputWatcher struct {
+	path    string
+	stat    func(string) (fs.FileInfo, error)
+	modTime time.Time
+}
+
+// changed reports whether the file was modified since the last call. The
+// first successful call always reports a change.
+func (w *inputWatcher) changed() (bool, error) {
+	info, err := w.stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(w.modTime) {
+		return false, nil
+	}
+	w.modTime = info.ModTime()
+	return true, nil
+}
+
+// watchFile regenerates output whenever input changes, logging each
+// regeneration and any errors; it never returns
+func watchFile(input, output string) {
+	watcher := &inputWatcher{path: input, stat: os.Stat}
+	for ; ; time.Sleep(watchInterval) {
+		changed, err := watcher.changed()
+		if err != nil {
+			log.Printf("Failed to check %s: %v", input, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := generateFile(input, output); err != nil {
+			log.Printf("Failed to regenerate from %s: %v", input, err)
+			continue
+		}
+		log.Printf("Regenerated %s from %s", output, input)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Tests for command line generation
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestInputWatcherChanged(t *testing.T) {
+	files := fstest.MapFS{"form.json": &fstest.MapFile{ModTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	watcher := &inputWatcher{path: "form.json", stat: func(name string) (fs.FileInfo, error) {
+		return fs.Stat(files, name)
+	}}
+
+	steps := []struct {
+		modTime time.Time
+		want    bool
+	}{
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC), true},
+		{time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC), false},
+	}
+	for i, step := range steps {
+		files["form.json"].ModTime = step.modTime
+		changed, err := watcher.changed()
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if changed != step.want {
+			t.Errorf("step %d: changed = %v, want %v", i, changed, step.want)
+		}
+	}
+
+	delete(files, "form.json")
+	if _, err := watcher.changed(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a not exist error for a removed file, got %v", err)
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "form.json")
+	output := filepath.Join(dir, "01-netcfg.yaml")
+	if err := os.WriteFile(input, []byte(`{"interfaces":[{"type":"ethernet","name":"eth0"}],"renderer":"networkd"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := generateFile(input, output); err != nil {
+		t.Fatalf("generateFile failed: %v", err)
+	}
+	yaml, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(yaml), "    eth0:\n      dhcp4: true\n") {
+		t.Errorf("Unexpected output:\n%s", yaml)
+	}
+
+	os.WriteFile(input, []byte(`{"interfaces":`), 0o600)
+	if err := generateFile(input, output); err == nil {
+		t.Errorf("Expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Tests for gzip compression of HTTP responses
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithGzip(t *testing.T) {
+	handler := withGzip(http.HandlerFunc(handleIndex))
+
+	// A gzip-capable client gets the page compressed
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate, gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected an HTML Content-Type, got %q", w.Header().Get("Content-Type"))
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Response is not gzip: %v", err)
+	}
+	compressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress response: %v", err)
+	}
+
+	// Other clients get the same page uncompressed
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected no Content-Encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Body.String() != string(compressed) {
+		t.Error("Expected the decompressed page to match the plain one")
+	}
+
+	// Short responses aren't worth compressing
+	req = httptest.NewRequest("GET", "/version", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	withGzip(http.HandlerFunc(handleVersion)).ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), "Netplan Web Generator") {
+		t.Errorf("Expected a short plain response, got %q with encoding %q", w.Body.String(), w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"deflate, br", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
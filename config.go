@@ -0,0 +1,346 @@
+/*
+Server configuration loaded from the environment
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Config holds the server settings read from the environment at startup
+type Config struct {
+	Port            string   // PORT, default 8080
+	BindAddr        string   // BIND_ADDR, default all interfaces
+	TLSCert         string   // TLS_CERT, certificate file; requires TLS_KEY
+	TLSKey          string   // TLS_KEY, private key file; requires TLS_CERT
+	LogFormat       string   // LOG_FORMAT, "text" (default) or "json"
+	RateLimit       int      // RATE_LIMIT, requests per minute per client; 0 disables
+	MaxBodyBytes    int64    // MAX_BODY_BYTES, default 1 MiB
+	MaxInterfaces   int      // MAX_INTERFACES, interfaces allowed per request, default 256
+	AllowedTypes    []string // ALLOWED_TYPES, comma-separated interface types generation accepts; empty allows all
+	DefaultBondMode string   // DEFAULT_BOND_MODE, mode for bonds that give none, default active-backup
+	AllowApply      bool     // ALLOW_APPLY, permits endpoints that run netplan on the host, such as /api/v1/apply-preview
+	Debug           bool     // DEBUG, enables developer endpoints under /debug/
+	BaseConfig      string   // BASE_CONFIG, netplan YAML file seeding every generated config
+	SaveDir         string   // SAVE_DIR, directory successful generations are saved to; empty disables
+	AutoRenderer    bool     // AUTO_RENDERER, detects the host's renderer for requests that give none
+	OutputTemplate  string   // OUTPUT_TEMPLATE, text/template file rendering the YAML instead of the built-in writer
+	ProfilesFile    string   // PROFILES_FILE, JSON file of named interface sets requests can start from
+	GenConcurrency  int      // GEN_CONCURRENCY, configs a batch generates at once, default the number of CPUs
+	StrictIfname    bool     // STRICT_IFNAME, rejects interface names and set-names over 15 characters instead of warning, default true
+
+	// Base is the parsed BASE_CONFIG file, or nil
+	Base *NetplanConfig
+	// Template is the parsed OUTPUT_TEMPLATE file, or nil
+	Template *template.Template
+	// Profiles are the parsed PROFILES_FILE, or nil
+	Profiles map[string][]InterfaceDefinition
+}
+
+// loadConfig reads the Config from the environment, applying defaults and
+// rejecting invalid values
+func loadConfig() (Config, error) {
+	config := Config{
+		Port:            "8080",
+		LogFormat:       "text",
+		MaxBodyBytes:    1 << 20,
+		MaxInterfaces:   256,
+		DefaultBondMode: "active-backup",
+		GenConcurrency:  runtime.NumCPU(),
+		StrictIfname:    true,
+	}
+
+	if port := os.Getenv("PORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return config, fmt.Errorf("invalid PORT %q: must be a number between 1 and 65535", port)
+		}
+		config.Port = port
+	}
+
+	if bindAddr := os.Getenv("BIND_ADDR"); bindAddr != "" {
+		if net.ParseIP(bindAddr) == nil && bindAddr != "localhost" {
+			return config, fmt.Errorf("invalid BIND_ADDR %q: must be an IP address", bindAddr)
+		}
+		config.BindAddr = bindAddr
+	}
+
+	config.TLSCert = os.Getenv("TLS_CERT")
+	config.TLSKey = os.Getenv("TLS_KEY")
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return config, fmt.Errorf("TLS_CERT and TLS_KEY must be set together")
+	}
+
+	if logFormat := os.Getenv("LOG_FORMAT"); logFormat != "" {
+		if logFormat != "text" && logFormat != "json" {
+			return config, fmt.Errorf("invalid LOG_FORMAT %q: must be text or json", logFormat)
+		}
+		config.LogFormat = logFormat
+	}
+
+	if rateLimit := os.Getenv("RATE_LIMIT"); rateLimit != "" {
+		n, err := strconv.Atoi(rateLimit)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("invalid RATE_LIMIT %q: must be a non-negative number of requests per minute", rateLimit)
+		}
+		config.RateLimit = n
+	}
+
+	if maxBodyBytes := os.Getenv("MAX_BODY_BYTES"); maxBodyBytes != "" {
+		n, err := strconv.ParseInt(maxBodyBytes, 10, 64)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid MAX_BODY_BYTES %q: must be a positive number", maxBodyBytes)
+		}
+		config.MaxBodyBytes = n
+	}
+
+	if maxInterfaces := os.Getenv("MAX_INTERFACES"); maxInterfaces != "" {
+		n, err := strconv.Atoi(maxInterfaces)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid MAX_INTERFACES %q: must be a positive number", maxInterfaces)
+		}
+		config.MaxInterfaces = n
+	}
+
+	if genConcurrency := os.Getenv("GEN_CONCURRENCY"); genConcurrency != "" {
+		n, err := strconv.Atoi(genConcurrency)
+		if err != nil || n <= 0 {
+			return config, fmt.Errorf("invalid GEN_CONCURRENCY %q: must be a positive number", genConcurrency)
+		}
+		config.GenConcurrency = n
+	}
+
+	for _, interfaceType := range parseCommaSeparated(os.Getenv("ALLOWED_TYPES")) {
+		if !hasKey(typeHandlers, interfaceType) {
+			return config, fmt.Errorf("invalid ALLOWED_TYPES %q: unknown interface type %q (expected %s)", os.Getenv("ALLOWED_TYPES"), interfaceType, strings.Join(sortedKeys(typeHandlers), ", "))
+		}
+		config.AllowedTypes = append(config.AllowedTypes, interfaceType)
+	}
+
+	if bondMode := os.Getenv("DEFAULT_BOND_MODE"); bondMode != "" {
+		mode, err := normalizeBondMode(bondMode)
+		if err != nil {
+			return config, fmt.Errorf("invalid DEFAULT_BOND_MODE %q: %v", bondMode, err)
+		}
+		config.DefaultBondMode = mode
+	}
+
+	if allowApply := os.Getenv("ALLOW_APPLY"); allowApply != "" {
+		b, err := strconv.ParseBool(allowApply)
+		if err != nil {
+			return config, fmt.Errorf("invalid ALLOW_APPLY %q: must be true or false", allowApply)
+		}
+		config.AllowApply = b
+	}
+
+	if debug := os.Getenv("DEBUG"); debug != "" {
+		b, err := strconv.ParseBool(debug)
+		if err != nil {
+			return config, fmt.Errorf("invalid DEBUG %q: must be true or false", debug)
+		}
+		config.Debug = b
+	}
+
+	if baseConfig := os.Getenv("BASE_CONFIG"); baseConfig != "" {
+		base, err := loadBaseConfig(baseConfig)
+		if err != nil {
+			return config, fmt.Errorf("invalid BASE_CONFIG %q: %v", baseConfig, err)
+		}
+		config.BaseConfig = baseConfig
+		config.Base = base
+	}
+
+	if autoRenderer := os.Getenv("AUTO_RENDERER"); autoRenderer != "" {
+		b, err := strconv.ParseBool(autoRenderer)
+		if err != nil {
+			return config, fmt.Errorf("invalid AUTO_RENDERER %q: must be true or false", autoRenderer)
+		}
+		config.AutoRenderer = b
+	}
+
+	if strictIfname := os.Getenv("STRICT_IFNAME"); strictIfname != "" {
+		b, err := strconv.ParseBool(strictIfname)
+		if err != nil {
+			return config, fmt.Errorf("invalid STRICT_IFNAME %q: must be true or false", strictIfname)
+		}
+		config.StrictIfname = b
+	}
+
+	if outputTemplate := os.Getenv("OUTPUT_TEMPLATE"); outputTemplate != "" {
+		tmpl, err := loadOutputTemplate(outputTemplate)
+		if err != nil {
+			return config, fmt.Errorf("invalid OUTPUT_TEMPLATE %q: %v", outputTemplate, err)
+		}
+		config.OutputTemplate = outputTemplate
+		config.Template = tmpl
+	}
+
+	if profilesFile := os.Getenv("PROFILES_FILE"); profilesFile != "" {
+		loaded, err := loadProfiles(profilesFile)
+		if err != nil {
+			return config, fmt.Errorf("invalid PROFILES_FILE %q: %v", profilesFile, err)
+		}
+		config.ProfilesFile = profilesFile
+		config.Profiles = loaded
+	}
+
+	if saveDir := os.Getenv("SAVE_DIR"); saveDir != "" {
+		info, err := os.Stat(saveDir)
+		if err != nil {
+			return config, fmt.Errorf("invalid SAVE_DIR %q: %v", saveDir, err)
+		}
+		if !info.IsDir() {
+			return config, fmt.Errorf("invalid SAVE_DIR %q: not a directory", saveDir)
+		}
+		config.SaveDir = saveDir
+	}
+
+	return config, nil
+}
+
+// loadBaseConfig reads a base netplan YAML file. Only its renderer, version
+// and network-level nameservers are used; interfaces are not allowed, as
+// they would be silently ignored.
+func loadBaseConfig(path string) (*NetplanConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	base, err := parseNetplanYAML(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	network := base.Network
+	if network.Version != 0 && network.Version != 2 {
+		return nil, fmt.Errorf("unsupported version %d", network.Version)
+	}
+	if network.Renderer != "" && network.Renderer != "networkd" && network.Renderer != "NetworkManager" {
+		return nil, fmt.Errorf("unknown renderer %q", network.Renderer)
+	}
+	if len(network.Ethernets)+len(network.Bonds)+len(network.Bridges)+len(network.VLANs)+len(network.DummyDevices)+len(network.Modems)+len(network.NMDevices) > 0 {
+		return nil, fmt.Errorf("interfaces are not allowed in a base config")
+	}
+	return base, nil
+}
+
+// rendererPattern matches a renderer key in an existing netplan file
+var rendererPattern = regexp.MustCompile(`^\s*renderer:\s*["']?(networkd|NetworkManager)["']?\s*(#.*)?$`)
+
+// detectRenderer picks the renderer for the host whose root filesystem is
+// fsys: the last renderer set in /etc/netplan, as netplan applies the files
+// in name order, or else NetworkManager if it is installed, or networkd.
+// The files are only scanned for renderer keys rather than parsed, as they
+// may use settings this generator doesn't support.
+func detectRenderer(fsys fs.FS) string {
+	renderer := ""
+	files, _ := fs.Glob(fsys, "etc/netplan/*.yaml")
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if match := rendererPattern.FindStringSubmatch(line); match != nil {
+				renderer = match[1]
+			}
+		}
+	}
+	if renderer != "" {
+		return renderer
+	}
+
+	for _, path := range []string{"usr/sbin/NetworkManager", "usr/bin/NetworkManager"} {
+		if _, err := fs.Stat(fsys, path); err == nil {
+			return "NetworkManager"
+		}
+	}
+	return "networkd"
+}
+
+// Addr returns the address to listen on
+func (c Config) Addr() string {
+	return net.JoinHostPort(c.BindAddr, c.Port)
+}
+
+// withLimits wraps a handler with the request body size limit and, when
+// enabled, the per-client rate limit
+func withLimits(next http.Handler, config Config) http.Handler {
+	limiter := newRateLimiter(config.RateLimit, time.Minute)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter != nil && !limiter.Allow(clientIP(r)) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, config.MaxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter allows a fixed number of requests per client in each window
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	start   time.Time
+	counts  map[string]int
+	nowFunc func() time.Time
+}
+
+// newRateLimiter returns nil when limit is 0, meaning no limit
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		counts:  make(map[string]int),
+		nowFunc: time.Now,
+	}
+}
+
+func (l *rateLimiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFunc()
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = make(map[string]int)
+	}
+
+	if l.counts[client] >= l.limit {
+		return false
+	}
+	l.counts[client]++
+	return true
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
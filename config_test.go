@@ -0,0 +1,221 @@
+/*
+Tests for server configuration loading
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func clearConfigEnv(t *testing.T) {
+	for _, name := range []string{"PORT", "BIND_ADDR", "TLS_CERT", "TLS_KEY", "LOG_FORMAT", "RATE_LIMIT", "MAX_BODY_BYTES", "MAX_INTERFACES", "ALLOWED_TYPES", "DEFAULT_BOND_MODE", "ALLOW_APPLY", "DEBUG", "BASE_CONFIG", "SAVE_DIR", "AUTO_RENDERER", "OUTPUT_TEMPLATE", "PROFILES_FILE", "GEN_CONCURRENCY", "STRICT_IFNAME"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	clearConfigEnv(t)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+
+	if config.Port != "8080" {
+		t.Errorf("Expected default port 8080, got %s", config.Port)
+	}
+	if config.Addr() != ":8080" {
+		t.Errorf("Expected default address :8080, got %s", config.Addr())
+	}
+	if config.LogFormat != "text" {
+		t.Errorf("Expected default log format text, got %s", config.LogFormat)
+	}
+	if config.MaxBodyBytes != 1<<20 {
+		t.Errorf("Expected default max body bytes 1048576, got %d", config.MaxBodyBytes)
+	}
+	if config.MaxInterfaces != 256 {
+		t.Errorf("Expected default max interfaces 256, got %d", config.MaxInterfaces)
+	}
+	if config.DefaultBondMode != "active-backup" {
+		t.Errorf("Expected default bond mode active-backup, got %q", config.DefaultBondMode)
+	}
+	if config.RateLimit != 0 || config.AllowApply || config.TLSCert != "" || config.TLSKey != "" {
+		t.Errorf("Expected rate limit, apply and TLS to be off by default, got %+v", config)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"PORT", "http"},
+		{"PORT", "70000"},
+		{"BIND_ADDR", "not an address"},
+		{"TLS_CERT", "/etc/ssl/cert.pem"},
+		{"LOG_FORMAT", "xml"},
+		{"RATE_LIMIT", "-1"},
+		{"MAX_BODY_BYTES", "0"},
+		{"MAX_INTERFACES", "none"},
+		{"ALLOWED_TYPES", "ethernet, wifi"},
+		{"DEFAULT_BOND_MODE", "fastest"},
+		{"ALLOW_APPLY", "maybe"},
+		{"OUTPUT_TEMPLATE", "/nonexistent/netplan.tmpl"},
+		{"PROFILES_FILE", "/nonexistent/profiles.json"},
+		{"GEN_CONCURRENCY", "0"},
+		{"STRICT_IFNAME", "sometimes"},
+	}
+
+	for _, test := range tests {
+		clearConfigEnv(t)
+		t.Setenv(test.name, test.value)
+
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("Expected error for %s=%q", test.name, test.value)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.nowFunc = func() time.Time { return now }
+
+	if !limiter.Allow("10.0.0.1") || !limiter.Allow("10.0.0.1") {
+		t.Fatalf("Expected first two requests to be allowed")
+	}
+	if limiter.Allow("10.0.0.1") {
+		t.Errorf("Expected third request in the window to be rejected")
+	}
+	if !limiter.Allow("10.0.0.2") {
+		t.Errorf("Expected other clients to have their own limit")
+	}
+
+	now = now.Add(time.Minute)
+	if !limiter.Allow("10.0.0.1") {
+		t.Errorf("Expected limit to reset in the next window")
+	}
+}
+
+func TestWithLimitsRejectsLargeBodies(t *testing.T) {
+	handler := withLimits(http.HandlerFunc(handleExplain), Config{MaxBodyBytes: 16})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/explain",
+		strings.NewReader(`{"interfaces":[{"type":"ethernet","name":"eth0"}]}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for oversized body, got %d", rec.Code)
+	}
+}
+
+func TestBaseConfig(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "base.yaml")
+	base := "# site defaults\nnetwork:\n  version: 2\n  renderer: NetworkManager\n  nameservers:\n    addresses: [10.0.0.53, 10.0.1.53]\n"
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BASE_CONFIG", path)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if config.Base == nil {
+		t.Fatal("Expected the base config to be loaded")
+	}
+
+	baseConfig = config.Base
+	t.Cleanup(func() { baseConfig = nil })
+
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "10.0.0.10/24"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.2.10/24", Nameservers: "1.1.1.1"},
+			{Type: "ethernet", Name: "eth2"},
+		},
+	}
+	generated, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	if generated.Network.Renderer != "NetworkManager" {
+		t.Errorf("Expected the base renderer NetworkManager, got %q", generated.Network.Renderer)
+	}
+	ethernets := generated.Network.Ethernets
+	if ns := ethernets["eth0"].Nameservers; ns == nil || strings.Join(ns.Addresses, ",") != "10.0.0.53,10.0.1.53" {
+		t.Errorf("Expected eth0 to get the base nameservers, got %+v", ns)
+	}
+	if ns := ethernets["eth1"].Nameservers; ns == nil || strings.Join(ns.Addresses, ",") != "1.1.1.1" {
+		t.Errorf("Expected eth1 to keep its own nameservers, got %+v", ns)
+	}
+	if ethernets["eth2"].Nameservers != nil {
+		t.Errorf("Expected DHCP interface eth2 to get no nameservers, got %+v", ethernets["eth2"].Nameservers)
+	}
+	if yaml := configToYAML(generated); strings.Count(yaml, "nameservers:") != 2 {
+		t.Errorf("Expected nameservers only under the interfaces, got:\n%s", yaml)
+	}
+
+	formData.Renderer = "networkd"
+	generated, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if generated.Network.Renderer != "networkd" {
+		t.Errorf("Expected the request renderer to override the base, got %q", generated.Network.Renderer)
+	}
+
+	if err := os.WriteFile(path, []byte("network:\n  ethernets:\n    eth0:\n      dhcp4: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected an error for a base config with interfaces")
+	}
+}
+
+func TestDetectRenderer(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"empty host", fstest.MapFS{}, "networkd"},
+		{"NetworkManager installed", fstest.MapFS{
+			"usr/sbin/NetworkManager": {Data: []byte{}},
+		}, "NetworkManager"},
+		{"netplan file wins", fstest.MapFS{
+			"usr/sbin/NetworkManager":          {Data: []byte{}},
+			"etc/netplan/50-cloud-init.yaml":   {Data: []byte("network:\n  version: 2\n  renderer: networkd # set by cloud-init\n")},
+			"etc/netplan/00-installer.yaml":    {Data: []byte("network:\n  renderer: NetworkManager\n")},
+			"etc/netplan/99-disabled.yaml.bak": {Data: []byte("network:\n  renderer: NetworkManager\n")},
+		}, "networkd"},
+		{"netplan file without renderer", fstest.MapFS{
+			"etc/netplan/01-netcfg.yaml": {Data: []byte("network:\n  ethernets:\n    eth0:\n      dhcp4: true\n")},
+		}, "networkd"},
+	}
+
+	for _, tt := range tests {
+		if got := detectRenderer(tt.fsys); got != tt.want {
+			t.Errorf("%s: detectRenderer() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Plain-English explanations of generated netplan configurations
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleExplain generates the config for the posted FormData and returns a
+// human-readable description of what each interface will do
+func handleExplain(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"explanation": explainConfig(config),
+	})
+}
+
+// explainConfig describes each interface of the config in one sentence,
+// ethernets first, then bonds, bridges, VLANs, dummy devices, modems and
+// NetworkManager passthrough devices; interface descriptions are included
+// after the name
+func explainConfig(config *NetplanConfig) []string {
+	var lines []string
+	add := func(name, line string) {
+		// Every line starts with the interface name, which is followed by
+		// the description when there is one
+		if description := config.Descriptions[name]; description != "" {
+			line = name + " (" + description + ")" + strings.TrimPrefix(line, name)
+		}
+		lines = append(lines, line)
+	}
+
+	memberOf := bondAndBridgeMembers(config.Network)
+
+	for _, name := range sortedKeys(config.Network.Ethernets) {
+		settings := config.Network.Ethernets[name].settings()
+		addressing := explainAddressing(settings)
+		if parent, ok := memberOf[name]; ok && addressing == "" {
+			add(name, fmt.Sprintf("%s is a member of %s with no addressing of its own", name, parent))
+			continue
+		}
+		if addressing == "" {
+			add(name, fmt.Sprintf("%s has no IP addressing configured%s", name, explainExtras(settings)))
+			continue
+		}
+		add(name, fmt.Sprintf("%s uses %s%s", name, addressing, explainExtras(settings)))
+	}
+
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		bond := config.Network.Bonds[name]
+		line := fmt.Sprintf("%s aggregates %s in %s mode", name, strings.Join(bond.Interfaces, ","), bond.Parameters.Mode)
+		if description := bondModeDescription(bond.Parameters.Mode); description != "" {
+			line += " (" + description + ")"
+		}
+		if addressing := explainAddressing(bond.settings()); addressing != "" {
+			line += " with " + addressing
+		}
+		add(name, line+explainExtras(bond.settings()))
+	}
+
+	for _, name := range sortedKeys(config.Network.Bridges) {
+		bridge := config.Network.Bridges[name]
+		line := fmt.Sprintf("%s bridges %s", name, strings.Join(bridge.Interfaces, ","))
+		if len(bridge.Interfaces) == 0 {
+			line = fmt.Sprintf("%s is a host-only bridge without members", name)
+		}
+		if addressing := explainAddressing(bridge.settings()); addressing != "" {
+			line += " with " + addressing
+		}
+		add(name, line+explainExtras(bridge.settings()))
+	}
+
+	for _, name := range sortedKeys(config.Network.VLANs) {
+		vlan := config.Network.VLANs[name]
+		line := fmt.Sprintf("%s is VLAN %d on %s", name, vlan.ID, vlan.Link)
+		if addressing := explainAddressing(vlan.settings()); addressing != "" {
+			line += " with " + addressing
+		}
+		add(name, line+explainExtras(vlan.settings()))
+	}
+
+	for _, name := range sortedKeys(config.Network.DummyDevices) {
+		settings := config.Network.DummyDevices[name].settings()
+		add(name, fmt.Sprintf("%s is a dummy device with %s%s", name, explainAddressing(settings), explainExtras(settings)))
+	}
+
+	for _, name := range sortedKeys(config.Network.Modems) {
+		modem := config.Network.Modems[name]
+		line := name + " is a modem"
+		if modem.APN != "" {
+			line += " on APN " + modem.APN
+		} else {
+			line += " with automatic APN configuration"
+		}
+		if addressing := explainAddressing(modem.settings()); addressing != "" {
+			line += " using " + addressing
+		}
+		add(name, line+explainExtras(modem.settings()))
+	}
+
+	for _, name := range sortedKeys(config.Network.NMDevices) {
+		passthrough := config.Network.NMDevices[name].NetworkManager.Passthrough
+		add(name, fmt.Sprintf("%s is passed through to NetworkManager with %d settings", name, len(passthrough)))
+	}
+
+	return lines
+}
+
+// bondModeDescriptions say what each bonding mode does with the members
+var bondModeDescriptions = map[string]string{
+	"balance-rr":    "round-robin over all members for load balancing and fault tolerance",
+	"active-backup": "one member carries traffic while the others stand by for failover",
+	"balance-xor":   "members chosen by a hash of the addresses for load balancing and fault tolerance",
+	"broadcast":     "every packet sent on all members for fault tolerance",
+	"802.3ad":       "LACP link aggregation requiring switch support",
+	"balance-tlb":   "outgoing traffic balanced by member load, without switch support",
+	"balance-alb":   "outgoing and incoming traffic balanced by member load, without switch support",
+}
+
+// bondModeDescription describes the bonding mode for an explanation, or
+// returns "" for a mode without a description
+func bondModeDescription(mode string) string {
+	return bondModeDescriptions[mode]
+}
+
+// explainAddressing describes how an interface gets its IP addresses, e.g.
+// "DHCP for IPv4" or "static IP 10.0.1.100/24"; it is empty when the
+// interface has no addressing at all
+func explainAddressing(s interfaceSettings) string {
+	var parts []string
+
+	dhcp4 := s.DHCP4 != nil && *s.DHCP4
+	dhcp6 := s.DHCP6 != nil && *s.DHCP6
+	switch {
+	case dhcp4 && dhcp6:
+		parts = append(parts, "DHCP for IPv4 and IPv6")
+	case dhcp4:
+		parts = append(parts, "DHCP for IPv4")
+	case dhcp6:
+		parts = append(parts, "DHCP for IPv6")
+	}
+
+	if len(s.Addresses) == 1 {
+		parts = append(parts, "static IP "+s.Addresses[0])
+	} else if len(s.Addresses) > 1 {
+		parts = append(parts, "static IPs "+strings.Join(s.Addresses, ", "))
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// explainExtras describes gateways, routes and DNS servers as a trailing
+// clause starting with a comma, or returns "" if there are none
+func explainExtras(s interfaceSettings) string {
+	var parts []string
+
+	if s.Gateway4 != "" {
+		parts = append(parts, "IPv4 gateway "+s.Gateway4)
+	}
+	if s.Gateway6 != "" {
+		parts = append(parts, "IPv6 gateway "+s.Gateway6)
+	}
+	for _, route := range s.Routes {
+		description := "a route to " + route.To
+		if route.Via != "" {
+			description += " via " + route.Via
+		}
+		parts = append(parts, description)
+	}
+	if s.Nameservers != nil && len(s.Nameservers.Addresses) > 0 {
+		parts = append(parts, "DNS servers "+strings.Join(s.Nameservers.Addresses, ", "))
+	} else if s.Nameservers != nil && s.Nameservers.Addresses != nil {
+		parts = append(parts, "no DNS servers")
+	}
+	if s.Nameservers != nil && len(s.Nameservers.Search) > 0 {
+		parts = append(parts, "search domains "+strings.Join(s.Nameservers.Search, ", "))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return ", using " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,140 @@
+/*
+Tests for plain-English config explanations
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplainBond(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type: "ethernet",
+				Name: "eth0",
+			},
+			{
+				Type:           "bond",
+				Name:           "bond0",
+				BondInterfaces: "eth1,eth2",
+				BondMode:       "802.3ad",
+				UseStatic:      true,
+				Addresses:      "10.0.1.100/24",
+				Gateway4:       "10.0.1.1",
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	explanation := strings.Join(explainConfig(config), "\n")
+
+	expectedPhrases := []string{
+		"eth0 uses DHCP for IPv4",
+		"bond0 aggregates eth1,eth2 in 802.3ad mode (LACP link aggregation requiring switch support) with static IP 10.0.1.100/24",
+		"IPv4 gateway 10.0.1.1",
+		"eth1 is a member of bond0",
+		"eth2 is a member of bond0",
+	}
+
+	for _, expected := range expectedPhrases {
+		if !strings.Contains(explanation, expected) {
+			t.Errorf("Expected explanation to contain %q, got:\n%s", expected, explanation)
+		}
+	}
+}
+
+func TestHandleExplain(t *testing.T) {
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0"}],"renderer":"networkd"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/explain", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handleExplain(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Explanation []string `json:"explanation"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(response.Explanation) != 1 || response.Explanation[0] != "eth0 uses DHCP for IPv4" {
+		t.Errorf("Unexpected explanation: %v", response.Explanation)
+	}
+}
+
+func TestDescriptionInCommentAndExplanation(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", Description: "Uplink to\ncore switch\t "},
+			{Type: "ethernet", Name: "eth1"},
+		},
+		Renderer: "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, "  ethernets:\n    # Uplink to core switch\n    eth0:\n") {
+		t.Errorf("Expected the sanitized description as a comment above eth0, got:\n%s", yaml)
+	}
+	if strings.Count(yaml, "#") != 1 {
+		t.Errorf("Expected only eth0 to have a comment, got:\n%s", yaml)
+	}
+	if _, err := parseNetplanYAML(yaml); err != nil {
+		t.Errorf("Failed to parse YAML with a description: %v", err)
+	}
+
+	explanation := explainConfig(config)
+	if len(explanation) != 2 || explanation[0] != "eth0 (Uplink to core switch) uses DHCP for IPv4" || !strings.HasPrefix(explanation[1], "eth1 uses") {
+		t.Errorf("Expected the description after eth0's name, got %q", explanation)
+	}
+}
+
+func TestBondModeDescription(t *testing.T) {
+	tests := map[string]string{
+		"802.3ad":       "LACP link aggregation requiring switch support",
+		"active-backup": "one member carries traffic while the others stand by for failover",
+		"unknown":       "",
+	}
+	for mode, expected := range tests {
+		if got := bondModeDescription(mode); got != expected {
+			t.Errorf("bondModeDescription(%q) = %q, want %q", mode, got, expected)
+		}
+	}
+
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: "active-backup"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	explanation := strings.Join(explainConfig(config), "\n")
+	expected := "bond0 aggregates eth0,eth1 in active-backup mode (one member carries traffic while the others stand by for failover)"
+	if !strings.Contains(explanation, expected) {
+		t.Errorf("Expected explanation to contain %q, got:\n%s", expected, explanation)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Import of form data from `ip -j addr` output
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ImportIPRouteRequest is the body of /api/v1/import/iproute; Dump is the
+// output of `ip -j addr`
+type ImportIPRouteRequest struct {
+	Dump        string `json:"dump"`
+	IncludeDown bool   `json:"includeDown"`
+	Renderer    string `json:"renderer"`
+}
+
+// ipAddrLink is one interface in `ip -j addr` output
+type ipAddrLink struct {
+	IfName    string       `json:"ifname"`
+	Flags     []string     `json:"flags"`
+	MTU       int          `json:"mtu"`
+	OperState string       `json:"operstate"`
+	LinkType  string       `json:"link_type"`
+	AddrInfo  []ipAddrInfo `json:"addr_info"`
+}
+
+type ipAddrInfo struct {
+	Family    string `json:"family"`
+	Local     string `json:"local"`
+	PrefixLen int    `json:"prefixlen"`
+	Scope     string `json:"scope"`
+	Dynamic   bool   `json:"dynamic"`
+}
+
+// handleImportIPRoute converts `ip -j addr` output into form data and
+// returns both the form data and the config generated from it
+func handleImportIPRoute(w http.ResponseWriter, r *http.Request) {
+	var request ImportIPRouteRequest
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+
+	formData, err := parseIPAddrJSON(request.Dump, request.IncludeDown)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	formData.Renderer = request.Renderer
+	writeConvertedFormData(w, r, formData)
+}
+
+// parseIPAddrJSON maps every interface in `ip -j addr` output to an
+// ethernet definition with its global addresses and MTU. Dynamic addresses
+// become DHCP, as they were most likely leased, and the rest are static;
+// since the form can't combine DHCP with static addresses, an interface
+// with a dynamic IPv4 address is imported as DHCP only. Loopback
+// interfaces are always skipped, and interfaces that are down unless
+// includeDown is set.
+func parseIPAddrJSON(dump string, includeDown bool) (FormData, error) {
+	var links []ipAddrLink
+	if err := json.Unmarshal([]byte(dump), &links); err != nil {
+		return FormData{}, fmt.Errorf("invalid ip -j addr output: %v", err)
+	}
+
+	var formData FormData
+	for _, link := range links {
+		if link.IfName == "" {
+			return formData, fmt.Errorf("invalid ip -j addr output: interface without ifname")
+		}
+		if link.LinkType == "loopback" || slices.Contains(link.Flags, "LOOPBACK") {
+			continue
+		}
+		if !includeDown && (link.OperState == "DOWN" || !slices.Contains(link.Flags, "UP")) {
+			continue
+		}
+
+		iface := InterfaceDefinition{Type: "ethernet", Name: link.IfName}
+		if link.MTU != 0 && link.MTU != 1500 {
+			iface.MTU = strconv.Itoa(link.MTU)
+		}
+
+		var addresses []string
+		dhcp4 := false
+		for _, addr := range link.AddrInfo {
+			if addr.Scope != "global" {
+				continue
+			}
+			switch {
+			case addr.Dynamic && addr.Family == "inet":
+				dhcp4 = true
+			case addr.Dynamic && addr.Family == "inet6":
+				iface.DHCP6 = true
+			default:
+				addresses = append(addresses, fmt.Sprintf("%s/%d", addr.Local, addr.PrefixLen))
+			}
+		}
+		if len(addresses) > 0 && !dhcp4 {
+			iface.UseStatic = true
+			iface.Addresses = strings.Join(addresses, ", ")
+		}
+		formData.Interfaces = append(formData.Interfaces, iface)
+	}
+
+	if len(formData.Interfaces) == 0 {
+		return formData, fmt.Errorf("no interfaces to import")
+	}
+	return formData, nil
+}
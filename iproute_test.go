@@ -0,0 +1,65 @@
+/*
+Tests for importing `ip -j addr` output
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+// ipAddrSample is trimmed `ip -j addr` output from a host with a loopback,
+// a DHCP uplink, a static interface and an unplugged port
+const ipAddrSample = `[
+  {"ifindex":1,"ifname":"lo","flags":["LOOPBACK","UP","LOWER_UP"],"mtu":65536,"qdisc":"noqueue","operstate":"UNKNOWN","group":"default","txqlen":1000,"link_type":"loopback","address":"00:00:00:00:00:00","broadcast":"00:00:00:00:00:00",
+   "addr_info":[{"family":"inet","local":"127.0.0.1","prefixlen":8,"scope":"host","label":"lo","valid_life_time":4294967295,"preferred_life_time":4294967295},
+                {"family":"inet6","local":"::1","prefixlen":128,"scope":"host","valid_life_time":4294967295,"preferred_life_time":4294967295}]},
+  {"ifindex":2,"ifname":"enp1s0","flags":["BROADCAST","MULTICAST","UP","LOWER_UP"],"mtu":1500,"qdisc":"fq_codel","operstate":"UP","group":"default","txqlen":1000,"link_type":"ether","address":"52:54:00:12:34:56","broadcast":"ff:ff:ff:ff:ff:ff",
+   "addr_info":[{"family":"inet","local":"192.168.122.45","prefixlen":24,"broadcast":"192.168.122.255","scope":"global","dynamic":true,"noprefixroute":true,"label":"enp1s0","valid_life_time":3188,"preferred_life_time":3188},
+                {"family":"inet6","local":"fe80::5054:ff:fe12:3456","prefixlen":64,"scope":"link","valid_life_time":4294967295,"preferred_life_time":4294967295}]},
+  {"ifindex":3,"ifname":"enp2s0","flags":["BROADCAST","MULTICAST","UP","LOWER_UP"],"mtu":9000,"qdisc":"fq_codel","operstate":"UP","group":"default","txqlen":1000,"link_type":"ether","address":"52:54:00:ab:cd:ef","broadcast":"ff:ff:ff:ff:ff:ff",
+   "addr_info":[{"family":"inet","local":"10.0.0.5","prefixlen":16,"broadcast":"10.0.255.255","scope":"global","label":"enp2s0","valid_life_time":4294967295,"preferred_life_time":4294967295},
+                {"family":"inet6","local":"2001:db8::5","prefixlen":64,"scope":"global","valid_life_time":4294967295,"preferred_life_time":4294967295}]},
+  {"ifindex":4,"ifname":"enp3s0","flags":["NO-CARRIER","BROADCAST","MULTICAST"],"mtu":1500,"qdisc":"noop","operstate":"DOWN","group":"default","txqlen":1000,"link_type":"ether","address":"52:54:00:00:00:04","broadcast":"ff:ff:ff:ff:ff:ff","addr_info":[]}
+]`
+
+func TestParseIPAddrJSON(t *testing.T) {
+	formData, err := parseIPAddrJSON(ipAddrSample, false)
+	if err != nil {
+		t.Fatalf("parseIPAddrJSON failed: %v", err)
+	}
+	if len(formData.Interfaces) != 2 {
+		t.Fatalf("Expected enp1s0 and enp2s0, got %+v", formData.Interfaces)
+	}
+
+	dhcp := formData.Interfaces[0]
+	if dhcp.Name != "enp1s0" || dhcp.UseStatic || dhcp.Addresses != "" || dhcp.MTU != "" {
+		t.Errorf("Expected enp1s0 to be imported as DHCP, got %+v", dhcp)
+	}
+	static := formData.Interfaces[1]
+	if static.Name != "enp2s0" || !static.UseStatic || static.Addresses != "10.0.0.5/16, 2001:db8::5/64" || static.MTU != "9000" {
+		t.Errorf("Expected enp2s0 with static addresses and MTU 9000, got %+v", static)
+	}
+
+	formData, err = parseIPAddrJSON(ipAddrSample, true)
+	if err != nil {
+		t.Fatalf("parseIPAddrJSON failed: %v", err)
+	}
+	if len(formData.Interfaces) != 3 || formData.Interfaces[2].Name != "enp3s0" {
+		t.Errorf("Expected the down enp3s0 to be included, got %+v", formData.Interfaces)
+	}
+
+	if _, err := generateNetplanConfig(FormData{Interfaces: formData.Interfaces, Renderer: "networkd"}); err != nil {
+		t.Errorf("Imported form data doesn't generate: %v", err)
+	}
+	if _, err := parseIPAddrJSON("not json", false); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
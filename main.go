@@ -24,133 +24,767 @@ package main
 
 import (
 	"embed"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"html"
 	"html/template"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
+// baseConfig seeds every generated config when BASE_CONFIG is set
+var baseConfig *NetplanConfig
+
+// maxInterfaces bounds the number of interfaces in a single request; it is
+// set from MAX_INTERFACES
+var maxInterfaces = 256
+
+// defaultBondMode is used for bonds that give no mode and preselected in
+// the form; it is set from DEFAULT_BOND_MODE
+var defaultBondMode = "active-backup"
+
+// allowedTypes are the interface types generation accepts, set from
+// ALLOWED_TYPES; empty allows all of typeHandlers
+var allowedTypes []string
+
+// strictIfname makes interface names and set-names longer than the
+// kernel's 15 characters an error rather than a warning; it is set from
+// STRICT_IFNAME
+var strictIfname = true
+
+// detectedRenderer is the renderer found on this host when AUTO_RENDERER
+// is set, used for requests that give none
+var detectedRenderer string
+
 //go:embed templates/*
 var templateFS embed.FS
 
+// indexTemplate is parsed once at startup; the templates are embedded, so
+// a parse error is a build problem and panics immediately rather than on
+// the first request. Parsed templates are safe for concurrent use.
+var indexTemplate = template.Must(template.ParseFS(templateFS, "templates/index.html"))
+
 // NetplanConfig represents the netplan configuration structure
 type NetplanConfig struct {
 	Network NetworkConfig `yaml:"network"`
+	
+	// Warnings collects non-fatal findings made while generating the
+	// config; they are reported to the user but never written to the YAML
+	Warnings []string `yaml:"-"`
+	
+	// IndentWidth is the number of spaces per nesting level in the written
+	// YAML; 0 means 2
+	IndentWidth int `yaml:"-"`
+	
+	// Descriptions are written as comments above the interfaces they are
+	// keyed by
+	Descriptions map[string]string `yaml:"-"`
+	
+	// MinimalOutput leaves out settings that are written with netplan's
+	// default value
+	MinimalOutput bool `yaml:"-"`
+	
+	// Explicit writes dhcp4 and dhcp6 on every interface, false when
+	// unset, and takes precedence over MinimalOutput
+	Explicit bool `yaml:"-"`
+	
+	// Disabled holds the disabled interfaces that are written after the
+	// config as a commented-out block, if any
+	Disabled *NetplanConfig `yaml:"-"`
+}
+
+func (c *NetplanConfig) warn(format string, args ...interface{}) {
+	c.Warnings = append(c.Warnings, fmt.Sprintf(format, args...))
+}
+
+// forEachInterface calls fn with the shared settings of every ethernet,
+// bond and bridge in name order, storing back any changes fn makes
+func (c *NetplanConfig) forEachInterface(fn func(name string, s *interfaceSettings)) {
+	for _, name := range sortedKeys(c.Network.Ethernets) {
+		eth := c.Network.Ethernets[name]
+		settings := eth.settings()
+		fn(name, &settings)
+		eth.setSettings(settings)
+		c.Network.Ethernets[name] = eth
+	}
+	for _, name := range sortedKeys(c.Network.Bonds) {
+		bond := c.Network.Bonds[name]
+		settings := bond.settings()
+		fn(name, &settings)
+		bond.setSettings(settings)
+		c.Network.Bonds[name] = bond
+	}
+	for _, name := range sortedKeys(c.Network.Bridges) {
+		bridge := c.Network.Bridges[name]
+		settings := bridge.settings()
+		fn(name, &settings)
+		bridge.setSettings(settings)
+		c.Network.Bridges[name] = bridge
+	}
+	for _, name := range sortedKeys(c.Network.VLANs) {
+		vlan := c.Network.VLANs[name]
+		settings := vlan.settings()
+		fn(name, &settings)
+		vlan.setSettings(settings)
+		c.Network.VLANs[name] = vlan
+	}
+	for _, name := range sortedKeys(c.Network.DummyDevices) {
+		dummy := c.Network.DummyDevices[name]
+		settings := dummy.settings()
+		fn(name, &settings)
+		dummy.setSettings(settings)
+		c.Network.DummyDevices[name] = dummy
+	}
+	for _, name := range sortedKeys(c.Network.Modems) {
+		modem := c.Network.Modems[name]
+		settings := modem.settings()
+		fn(name, &settings)
+		modem.setSettings(settings)
+		c.Network.Modems[name] = modem
+	}
 }
 
 type NetworkConfig struct {
 	Version   int                       `yaml:"version"`
-	Renderer  string                    `yaml:"renderer"`
+	Renderer  string                    `yaml:"renderer,omitempty"`
 	Ethernets map[string]EthernetConfig `yaml:"ethernets,omitempty"`
 	Bonds     map[string]BondConfig     `yaml:"bonds,omitempty"`
 	Bridges   map[string]BridgeConfig   `yaml:"bridges,omitempty"`
+	VLANs     map[string]VLANConfig     `yaml:"vlans,omitempty"`
+	// DummyDevices are virtual interfaces that only carry addresses, such
+	// as a loopback-style /32 for a service or routing daemon
+	DummyDevices map[string]EthernetConfig `yaml:"dummy-devices,omitempty"`
+	Modems       map[string]ModemConfig    `yaml:"modems,omitempty"`
+	NMDevices    map[string]NMDeviceConfig `yaml:"nm-devices,omitempty"`
+	// Nameservers is only read from the BASE_CONFIG file, which uses it
+	// for interfaces with static addresses and no nameservers of their
+	// own; netplan has no network-wide nameservers, so it is never written
+	Nameservers *NameserversConfig `yaml:"nameservers,omitempty"`
+	// SectionRenderers overrides Renderer for every interface in a
+	// section, keyed by section name (e.g. "modems"); it is written as a
+	// renderer key inside the section rather than as a field of its own
+	SectionRenderers map[string]string `yaml:"-"`
+}
+
+// typeHandlers add an interface of the form to the config, keyed by the
+// interface type they handle; they are the types generation supports
+var typeHandlers = make(map[string]func(*NetplanConfig, InterfaceDefinition) error)
+
+// registerInterfaceType makes generation accept interfaces of the given
+// type, adding them with handler. Types are registered at init, so
+// registering one twice is a programming error and panics.
+func registerInterfaceType(interfaceType string, handler func(*NetplanConfig, InterfaceDefinition) error) {
+	if hasKey(typeHandlers, interfaceType) {
+		panic("interface type registered twice: " + interfaceType)
+	}
+	typeHandlers[interfaceType] = handler
+}
+
+func init() {
+	registerInterfaceType("ethernet", addEthernetToConfig)
+	registerInterfaceType("bond", addBondToConfig)
+	registerInterfaceType("bridge", addBridgeToConfig)
+	registerInterfaceType("vlan", addVLANToConfig)
+	registerInterfaceType("dummy", addDummyToConfig)
+	registerInterfaceType("modem", addModemToConfig)
+	registerInterfaceType("nm-device", addNMDeviceToConfig)
+}
+
+// interfaceSections are the netplan sections holding interface
+// definitions, in the order they are written
+var interfaceSections = []string{"ethernets", "bonds", "bridges", "vlans", "dummy-devices", "modems", "nm-devices"}
+
+// interfaceRenderer returns the renderer used for the named interface
+func (n NetworkConfig) interfaceRenderer(name string) string {
+	switch {
+	case hasKey(n.Ethernets, name):
+		return n.sectionRenderer("ethernets")
+	case hasKey(n.Bonds, name):
+		return n.sectionRenderer("bonds")
+	case hasKey(n.Bridges, name):
+		return n.sectionRenderer("bridges")
+	case hasKey(n.VLANs, name):
+		return n.sectionRenderer("vlans")
+	case hasKey(n.DummyDevices, name):
+		return n.sectionRenderer("dummy-devices")
+	case hasKey(n.Modems, name):
+		return n.sectionRenderer("modems")
+	}
+	return n.Renderer
+}
+
+// sectionRenderer returns the renderer used for the interfaces in section
+func (n NetworkConfig) sectionRenderer(section string) string {
+	if renderer, ok := n.SectionRenderers[section]; ok {
+		return renderer
+	}
+	return n.Renderer
 }
 
 type EthernetConfig struct {
+	Match           *MatchConfig           `yaml:"match,omitempty"`
+	SetName         string                 `yaml:"set-name,omitempty"`
+	MACAddress      string                 `yaml:"macaddress,omitempty"`
+	Optional        bool                   `yaml:"optional,omitempty"`
+	Critical        bool                   `yaml:"critical,omitempty"`
+	MTU             int                    `yaml:"mtu,omitempty"`
 	DHCP4           *bool                  `yaml:"dhcp4,omitempty"`
 	DHCP6           *bool                  `yaml:"dhcp6,omitempty"`
+	AcceptRA        *bool                  `yaml:"accept-ra,omitempty"`
+	LinkLocal       []string               `yaml:"link-local,omitempty"`
 	Addresses       []string               `yaml:"addresses,omitempty"`
 	Gateway4        string                 `yaml:"gateway4,omitempty"`
 	Gateway6        string                 `yaml:"gateway6,omitempty"`
+	Routes          []Route                `yaml:"routes,omitempty"`
 	Nameservers     *NameserversConfig     `yaml:"nameservers,omitempty"`
 	DHCP4Overrides  map[string]interface{} `yaml:"dhcp4-overrides,omitempty"`
 	DHCP6Overrides  map[string]interface{} `yaml:"dhcp6-overrides,omitempty"`
+	// NetworkManager holds settings passed through to NetworkManager,
+	// such as the one for promiscuous mode
+	NetworkManager *NMSettings `yaml:"networkmanager,omitempty"`
+	// Promiscuous has no netplan key; see promiscuousPassthrough
+	Promiscuous bool `yaml:"-"`
+}
+
+// MatchConfig selects the physical device an ethernet applies to, so its
+// key is only an ID and set-name can rename the device
+type MatchConfig struct {
+	MACAddress string `yaml:"macaddress"`
 }
 
 type BondConfig struct {
 	Interfaces  []string           `yaml:"interfaces"`
 	Parameters  BondParameters     `yaml:"parameters"`
+	MACAddress  string             `yaml:"macaddress,omitempty"`
+	Optional    bool               `yaml:"optional,omitempty"`
+	Critical    bool               `yaml:"critical,omitempty"`
+	MTU         int                `yaml:"mtu,omitempty"`
 	DHCP4       *bool              `yaml:"dhcp4,omitempty"`
 	DHCP6       *bool              `yaml:"dhcp6,omitempty"`
+	AcceptRA    *bool              `yaml:"accept-ra,omitempty"`
+	LinkLocal   []string           `yaml:"link-local,omitempty"`
 	Addresses   []string           `yaml:"addresses,omitempty"`
 	Gateway4    string             `yaml:"gateway4,omitempty"`
 	Gateway6    string             `yaml:"gateway6,omitempty"`
+	Routes      []Route            `yaml:"routes,omitempty"`
 	Nameservers *NameserversConfig `yaml:"nameservers,omitempty"`
 }
 
 type BridgeConfig struct {
-	Interfaces  []string           `yaml:"interfaces"`
+	Interfaces  []string           `yaml:"interfaces,omitempty"`
+	MACAddress  string             `yaml:"macaddress,omitempty"`
+	Optional    bool               `yaml:"optional,omitempty"`
+	Critical    bool               `yaml:"critical,omitempty"`
+	MTU         int                `yaml:"mtu,omitempty"`
+	DHCP4       *bool              `yaml:"dhcp4,omitempty"`
+	DHCP6       *bool              `yaml:"dhcp6,omitempty"`
+	AcceptRA    *bool              `yaml:"accept-ra,omitempty"`
+	LinkLocal   []string           `yaml:"link-local,omitempty"`
+	Addresses   []string           `yaml:"addresses,omitempty"`
+	Gateway4    string             `yaml:"gateway4,omitempty"`
+	Gateway6    string             `yaml:"gateway6,omitempty"`
+	Routes      []Route            `yaml:"routes,omitempty"`
+	Nameservers *NameserversConfig `yaml:"nameservers,omitempty"`
+}
+
+// VLANConfig is an 802.1Q VLAN carrying the traffic tagged with ID on its
+// Link interface
+type VLANConfig struct {
+	ID          int                `yaml:"id"`
+	Link        string             `yaml:"link"`
+	MACAddress  string             `yaml:"macaddress,omitempty"`
+	Optional    bool               `yaml:"optional,omitempty"`
+	Critical    bool               `yaml:"critical,omitempty"`
+	MTU         int                `yaml:"mtu,omitempty"`
 	DHCP4       *bool              `yaml:"dhcp4,omitempty"`
 	DHCP6       *bool              `yaml:"dhcp6,omitempty"`
+	AcceptRA    *bool              `yaml:"accept-ra,omitempty"`
+	LinkLocal   []string           `yaml:"link-local,omitempty"`
 	Addresses   []string           `yaml:"addresses,omitempty"`
 	Gateway4    string             `yaml:"gateway4,omitempty"`
 	Gateway6    string             `yaml:"gateway6,omitempty"`
+	Routes      []Route            `yaml:"routes,omitempty"`
+	Nameservers *NameserversConfig `yaml:"nameservers,omitempty"`
+}
+
+// ModemConfig is a cellular modem managed through ModemManager; either
+// AutoConfig or an APN is required
+type ModemConfig struct {
+	APN         string             `yaml:"apn,omitempty"`
+	AutoConfig  bool               `yaml:"auto-config,omitempty"`
+	Number      string             `yaml:"number,omitempty"`
+	PIN         string             `yaml:"pin,omitempty"`
+	Optional    bool               `yaml:"optional,omitempty"`
+	MTU         int                `yaml:"mtu,omitempty"`
+	DHCP4       *bool              `yaml:"dhcp4,omitempty"`
+	DHCP6       *bool              `yaml:"dhcp6,omitempty"`
 	Nameservers *NameserversConfig `yaml:"nameservers,omitempty"`
 }
 
+// NMDeviceConfig is a NetworkManager connection that netplan's structured
+// model can't express; its settings are passed through to NetworkManager
+// unchanged as "section.key" pairs
+type NMDeviceConfig struct {
+	NetworkManager NMSettings `yaml:"networkmanager"`
+}
+
+type NMSettings struct {
+	Passthrough map[string]string `yaml:"passthrough"`
+}
+
 type BondParameters struct {
-	Mode string `yaml:"mode"`
+	Mode               string `yaml:"mode"`
+	MIIMonitorInterval int    `yaml:"mii-monitor-interval,omitempty"`
+	UpDelay            int    `yaml:"up-delay,omitempty"`
+	DownDelay          int    `yaml:"down-delay,omitempty"`
+	MinLinks           int    `yaml:"min-links,omitempty"`
+	// AllMembersActive delivers duplicate frames received on backup
+	// members instead of dropping them
+	AllMembersActive *bool `yaml:"all-members-active,omitempty"`
+}
+
+type Route struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via,omitempty"`
+	Metric int    `yaml:"metric,omitempty"`
+	Table  int    `yaml:"table,omitempty"`
+	MTU    int    `yaml:"mtu,omitempty"`
+	OnLink bool   `yaml:"on-link,omitempty"`
 }
 
+// NameserversConfig is an interface's DNS settings. Addresses is nil when
+// none are given and empty, but not nil, when they are explicitly cleared,
+// which is written as addresses: [].
 type NameserversConfig struct {
-	Addresses []string `yaml:"addresses"`
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+// interfaceSettings holds the settings shared by every interface section,
+// so they can be written out by a single function
+type interfaceSettings struct {
+	MACAddress     string
+	Optional       bool
+	Critical       bool
+	MTU            int
+	DHCP4          *bool
+	DHCP6          *bool
+	AcceptRA       *bool
+	LinkLocal      []string
+	Addresses      []string
+	Gateway4       string
+	Gateway6       string
+	Routes         []Route
+	Nameservers    *NameserversConfig
+	DHCP4Overrides map[string]interface{}
+	DHCP6Overrides map[string]interface{}
+	// Promiscuous is only used by the networkd and NetworkManager exports
+	Promiscuous bool
+}
+
+func (e EthernetConfig) settings() interfaceSettings {
+	return interfaceSettings{
+		MACAddress:     e.MACAddress,
+		Optional:       e.Optional,
+		Critical:       e.Critical,
+		MTU:            e.MTU,
+		DHCP4:          e.DHCP4,
+		DHCP6:          e.DHCP6,
+		AcceptRA:       e.AcceptRA,
+		LinkLocal:      e.LinkLocal,
+		Addresses:      e.Addresses,
+		Gateway4:       e.Gateway4,
+		Gateway6:       e.Gateway6,
+		Routes:         e.Routes,
+		Nameservers:    e.Nameservers,
+		DHCP4Overrides: e.DHCP4Overrides,
+		DHCP6Overrides: e.DHCP6Overrides,
+		Promiscuous:    e.Promiscuous,
+	}
+}
+
+func (b BondConfig) settings() interfaceSettings {
+	return interfaceSettings{
+		MACAddress:  b.MACAddress,
+		Optional:    b.Optional,
+		Critical:    b.Critical,
+		MTU:         b.MTU,
+		DHCP4:       b.DHCP4,
+		DHCP6:       b.DHCP6,
+		AcceptRA:    b.AcceptRA,
+		LinkLocal:   b.LinkLocal,
+		Addresses:   b.Addresses,
+		Gateway4:    b.Gateway4,
+		Gateway6:    b.Gateway6,
+		Routes:      b.Routes,
+		Nameservers: b.Nameservers,
+	}
+}
+
+func (b BridgeConfig) settings() interfaceSettings {
+	return interfaceSettings{
+		MACAddress:  b.MACAddress,
+		Optional:    b.Optional,
+		Critical:    b.Critical,
+		MTU:         b.MTU,
+		DHCP4:       b.DHCP4,
+		DHCP6:       b.DHCP6,
+		AcceptRA:    b.AcceptRA,
+		LinkLocal:   b.LinkLocal,
+		Addresses:   b.Addresses,
+		Gateway4:    b.Gateway4,
+		Gateway6:    b.Gateway6,
+		Routes:      b.Routes,
+		Nameservers: b.Nameservers,
+	}
+}
+
+func (v VLANConfig) settings() interfaceSettings {
+	return interfaceSettings{
+		MACAddress:  v.MACAddress,
+		Optional:    v.Optional,
+		Critical:    v.Critical,
+		MTU:         v.MTU,
+		DHCP4:       v.DHCP4,
+		DHCP6:       v.DHCP6,
+		AcceptRA:    v.AcceptRA,
+		LinkLocal:   v.LinkLocal,
+		Addresses:   v.Addresses,
+		Gateway4:    v.Gateway4,
+		Gateway6:    v.Gateway6,
+		Routes:      v.Routes,
+		Nameservers: v.Nameservers,
+	}
+}
+
+func (m ModemConfig) settings() interfaceSettings {
+	return interfaceSettings{
+		Optional:    m.Optional,
+		MTU:         m.MTU,
+		DHCP4:       m.DHCP4,
+		DHCP6:       m.DHCP6,
+		Nameservers: m.Nameservers,
+	}
+}
+
+func (e *EthernetConfig) setSettings(s interfaceSettings) {
+	e.MACAddress = s.MACAddress
+	e.Optional = s.Optional
+	e.Critical = s.Critical
+	e.MTU = s.MTU
+	e.DHCP4 = s.DHCP4
+	e.DHCP6 = s.DHCP6
+	e.AcceptRA = s.AcceptRA
+	e.LinkLocal = s.LinkLocal
+	e.Addresses = s.Addresses
+	e.Gateway4 = s.Gateway4
+	e.Gateway6 = s.Gateway6
+	e.Routes = s.Routes
+	e.Nameservers = s.Nameservers
+	e.DHCP4Overrides = s.DHCP4Overrides
+	e.DHCP6Overrides = s.DHCP6Overrides
+	e.Promiscuous = s.Promiscuous
+}
+
+func (b *BondConfig) setSettings(s interfaceSettings) {
+	b.MACAddress = s.MACAddress
+	b.Optional = s.Optional
+	b.Critical = s.Critical
+	b.MTU = s.MTU
+	b.DHCP4 = s.DHCP4
+	b.DHCP6 = s.DHCP6
+	b.AcceptRA = s.AcceptRA
+	b.LinkLocal = s.LinkLocal
+	b.Addresses = s.Addresses
+	b.Gateway4 = s.Gateway4
+	b.Gateway6 = s.Gateway6
+	b.Routes = s.Routes
+	b.Nameservers = s.Nameservers
+}
+
+func (b *BridgeConfig) setSettings(s interfaceSettings) {
+	b.MACAddress = s.MACAddress
+	b.Optional = s.Optional
+	b.Critical = s.Critical
+	b.MTU = s.MTU
+	b.DHCP4 = s.DHCP4
+	b.DHCP6 = s.DHCP6
+	b.AcceptRA = s.AcceptRA
+	b.LinkLocal = s.LinkLocal
+	b.Addresses = s.Addresses
+	b.Gateway4 = s.Gateway4
+	b.Gateway6 = s.Gateway6
+	b.Routes = s.Routes
+	b.Nameservers = s.Nameservers
+}
+
+func (v *VLANConfig) setSettings(s interfaceSettings) {
+	v.MACAddress = s.MACAddress
+	v.Optional = s.Optional
+	v.Critical = s.Critical
+	v.MTU = s.MTU
+	v.DHCP4 = s.DHCP4
+	v.DHCP6 = s.DHCP6
+	v.AcceptRA = s.AcceptRA
+	v.LinkLocal = s.LinkLocal
+	v.Addresses = s.Addresses
+	v.Gateway4 = s.Gateway4
+	v.Gateway6 = s.Gateway6
+	v.Routes = s.Routes
+	v.Nameservers = s.Nameservers
+}
+
+func (m *ModemConfig) setSettings(s interfaceSettings) {
+	m.Optional = s.Optional
+	m.MTU = s.MTU
+	m.DHCP4 = s.DHCP4
+	m.DHCP6 = s.DHCP6
+	m.Nameservers = s.Nameservers
 }
 
 // InterfaceDefinition represents a single interface configuration
 type InterfaceDefinition struct {
 	Type             string `json:"type"`
 	Name             string `json:"name"`
+	MACAddress       string `json:"macaddress"`
+	Description      string `json:"description,omitempty"`
+	MatchMAC         string `json:"matchMacaddress,omitempty"`
+	SetName          string `json:"setName,omitempty"`
+	Promiscuous      bool   `json:"promiscuous,omitempty"`
+	Disabled         bool   `json:"disabled,omitempty"`
 	UseStatic        bool   `json:"useStatic"`
+	IPv4Only         bool   `json:"ipv4Only"`
+	IPv6Only         bool   `json:"ipv6Only"`
+	DHCP4            *bool  `json:"dhcp4,omitempty"`
+	DHCP6            bool   `json:"dhcp6"`
+	Optional         bool   `json:"optional"`
+	Critical         bool   `json:"critical"`
+	MTU              string `json:"mtu"`
+	AcceptRA         *bool  `json:"acceptRA,omitempty"`
+	LinkLocal        string `json:"linkLocal"`
 	Addresses        string `json:"addresses"`
 	Gateway4         string `json:"gateway4"`
 	Gateway6         string `json:"gateway6"`
+	Routes           string `json:"routes"`
+	RoutesCSV        string `json:"routesCSV"`
 	Nameservers      string `json:"nameservers"`
+	Nameservers4     string `json:"nameservers4"`
+	Nameservers6     string `json:"nameservers6"`
+	SearchDomains    string `json:"searchDomains"`
+	ClearNameservers bool   `json:"clearNameservers,omitempty"`
 	DHCP4Overrides   string `json:"dhcp4Overrides"`
 	DHCP6Overrides   string `json:"dhcp6Overrides"`
+	DHCP4UseDNS      *bool  `json:"dhcp4UseDNS,omitempty"`
+	DHCP4RouteMetric string `json:"dhcp4RouteMetric"`
+	DHCP6UseDNS      *bool  `json:"dhcp6UseDNS,omitempty"`
+	DHCP6RouteMetric string `json:"dhcp6RouteMetric"`
 	BondInterfaces   string `json:"bondInterfaces"`
 	BondMode         string `json:"bondMode"`
+	BondMIIMonitor   string `json:"bondMiiMonitorInterval"`
+	BondUpDelay      string `json:"bondUpDelay"`
+	BondDownDelay    string `json:"bondDownDelay"`
+	BondMinLinks     string `json:"bondMinLinks"`
+	BondAllActive    *bool  `json:"bondAllMembersActive,omitempty"`
+	JumboFrames      bool   `json:"jumboFrames,omitempty"`
 	BridgeInterfaces string `json:"bridgeInterfaces"`
+	AllowMemberIPv6  bool   `json:"allowMemberIPv6"`
+	VLANID           string `json:"vlanId"`
+	VLANLink         string `json:"vlanLink"`
+	ModemAPN         string `json:"modemApn"`
+	ModemAutoConfig  bool   `json:"modemAutoConfig"`
+	ModemNumber      string `json:"modemNumber"`
+	ModemPIN         string `json:"modemPin"`
+	NMPassthrough    string `json:"nmPassthrough"`
 }
 
 // FormData represents the web form input
 type FormData struct {
-	Interfaces []InterfaceDefinition `json:"interfaces"`
-	Renderer   string                `json:"renderer"`
+	Interfaces    []InterfaceDefinition `json:"interfaces"`
+	Renderer      string                `json:"renderer"`
+	TargetRelease string                `json:"targetRelease"`
+	// EmbedSource appends this FormData to the YAML as a comment, so the
+	// config can be loaded back into the form and edited later
+	EmbedSource bool `json:"embedSource,omitempty"`
+	// IndentWidth is the number of spaces per YAML nesting level, 2 (the
+	// default) or 4
+	IndentWidth int `json:"indentWidth,omitempty"`
+	// OmitTrailingNewline drops the newline that otherwise ends the YAML,
+	// for consumers that can't handle one
+	OmitTrailingNewline bool `json:"omitTrailingNewline,omitempty"`
+	// MinimalOutput leaves out settings whose value is netplan's default,
+	// such as dhcp4: false
+	MinimalOutput bool `json:"minimalOutput,omitempty"`
+	// Explicit writes dhcp4 and dhcp6 on every interface with their
+	// resolved value, even where that is netplan's default, overriding
+	// MinimalOutput
+	Explicit bool `json:"explicit,omitempty"`
+	// NameserverOrder sorts every interface's nameservers by family,
+	// "ipv4-first" or "ipv6-first", keeping the input order within each
+	// family; empty keeps the input order
+	NameserverOrder string `json:"nameserverOrder,omitempty"`
+	// CommentDisabled writes disabled interfaces after the config as a
+	// commented-out block, instead of leaving them out entirely
+	CommentDisabled bool `json:"commentDisabled,omitempty"`
+	// SectionRenderers sets the renderer for all interfaces of a section,
+	// keyed by section name such as "ethernets" or "modems"
+	SectionRenderers map[string]string `json:"sectionRenderers,omitempty"`
+	// Profile seeds the interfaces from a profile of PROFILES_FILE; the
+	// interfaces given override or add to the profile's
+	Profile string `json:"profile,omitempty"`
+	// BondModeDefault is the mode for bonds of this request that give
+	// none, taking precedence over DEFAULT_BOND_MODE
+	BondModeDefault string `json:"bondModeDefault,omitempty"`
+	// IdentifyByMAC matches every ethernet by its MAC address in
+	// InterfaceMACs and renames it to its name with set-name, for images
+	// where interface names aren't stable
+	IdentifyByMAC bool              `json:"identifyByMAC,omitempty"`
+	InterfaceMACs map[string]string `json:"interfaceMACs,omitempty"`
+	// MaintenanceInterface names an interface that always gets DHCPv4 and
+	// is never optional, so the host stays reachable through it
+	MaintenanceInterface string `json:"maintenanceInterface,omitempty"`
+	// AllStatic turns DHCP off on every interface, for networks without a
+	// DHCP server; each interface then needs static addresses
+	AllStatic bool `json:"allStatic,omitempty"`
 }
 
 // PageData represents data passed to the template
 type PageData struct {
-	FormData FormData
-	Output   string
-	Error    string
+	FormData        FormData
+	Output          string
+	Error           string
+	Warnings        []string
+	DefaultBondMode string
+}
+
+// releaseCapability describes what the netplan shipped with an Ubuntu
+// release supports
+type releaseCapability struct {
+	Netplan     string
+	GatewayKeys bool // gateway4/gateway6 are accepted without deprecation
+}
+
+// releaseCapabilities is keyed by Ubuntu release
+var releaseCapabilities = map[string]releaseCapability{
+	"20.04": {Netplan: "0.103", GatewayKeys: true},
+	"22.04": {Netplan: "0.104", GatewayKeys: false},
+	"24.04": {Netplan: "1.0", GatewayKeys: false},
 }
 
 func main() {
 	http.HandleFunc("/", handleIndex)
 	http.HandleFunc("/generate", handleGenerate)
+	http.HandleFunc("/preview", handlePreview)
+	http.HandleFunc("/api/v1/explain", handleExplain)
+	http.HandleFunc("/api/v1/set", handleSet)
+	http.HandleFunc("/api/v1/shorthand", handleShorthand)
+	http.HandleFunc("/api/v1/import/iproute", handleImportIPRoute)
+	http.HandleFunc("/api/v1/allocate", handleAllocate)
+	http.HandleFunc("/api/v1/teardown", handleTeardown)
+	http.HandleFunc("/api/v1/changed", handleChanged)
+	http.HandleFunc("/api/v1/split", handleSplit)
+	http.HandleFunc("/api/v1/merge", handleMerge)
+	http.HandleFunc("/api/v1/batch", handleBatch)
+	http.HandleFunc("/api/v1/capabilities", handleCapabilities)
+	http.HandleFunc("/api/v1/export/networkd", handleExportNetworkd)
+	http.HandleFunc("/api/v1/export/nmkeyfile", handleExportNMKeyfile)
+	http.HandleFunc("/download/bundle", handleDownloadBundle)
 	http.HandleFunc("/version", handleVersion)
 	
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	input := flag.String("input", "", "generate from this JSON form data file instead of starting the server")
+	output := flag.String("output", "", "file to write the generated YAML to, instead of stdout")
+	watch := flag.Bool("watch", false, "regenerate whenever the -input file changes")
+	tui := flag.Bool("tui", false, "prompt for the interfaces on the terminal and print the generated YAML")
+	flag.Parse()
+	
+	config, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if config.Debug {
+		http.HandleFunc("/debug/selftest", handleSelftest)
+	}
+	baseConfig = config.Base
+	maxInterfaces = config.MaxInterfaces
+	allowedTypes = config.AllowedTypes
+	defaultBondMode = config.DefaultBondMode
+	outputTemplate = config.Template
+	profiles = config.Profiles
+	genConcurrency = config.GenConcurrency
+	allowApply = config.AllowApply
+	strictIfname = config.StrictIfname
+	if config.AutoRenderer {
+		detectedRenderer = detectRenderer(os.DirFS("/"))
+		log.Printf("Detected renderer %s", detectedRenderer)
+	}
+	
+	if *watch && (*input == "" || *output == "") {
+		log.Fatal("-watch requires -input and -output")
+	}
+	if *watch {
+		watchFile(*input, *output)
+	}
+	if *tui {
+		if err := runTUI(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *input != "" {
+		if err := generateFile(*input, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if config.SaveDir != "" {
+		savedConfigs = newConfigStore(config.SaveDir)
+		http.HandleFunc("/saved/", handleSaved)
+	}
+	if path, err := exec.LookPath("netplan"); err == nil {
+		netplanPath = path
+		http.HandleFunc("/api/v1/apply-preview", handleApplyPreview)
+	}
+	
+	if config.LogFormat == "json" {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
 	}
 	
 	log.Printf("Netplan Web Generator v1.0.0")
 	log.Printf("Copyright (C) 2025 Michael Tinsay")
 	log.Printf("Licensed under GPLv3 - https://www.gnu.org/licenses/gpl-3.0.html")
-	log.Printf("Starting server on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Printf("Starting server on %s", config.Addr())
+	
+	handler := withRequestID(withGzip(withLimits(http.DefaultServeMux, config)))
+	if config.TLSCert != "" {
+		log.Fatal(http.ListenAndServeTLS(config.Addr(), config.TLSCert, config.TLSKey, handler))
+	}
+	log.Fatal(http.ListenAndServe(config.Addr(), handler))
 }
 
 func handleIndex(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFS(templateFS, "templates/index.html")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	
 	data := PageData{
 		FormData: FormData{
 			Renderer: "networkd",
-			BondMode: "active-backup",
 		},
+		DefaultBondMode: defaultBondMode,
 	}
 	
-	tmpl.Execute(w, data)
+	indexTemplate.Execute(w, data)
 }
 
 func handleVersion(w http.ResponseWriter, r *http.Request) {
@@ -167,6 +801,21 @@ func handleVersion(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(version))
 }
 
+// writeEncodedYAML writes yamlOutput for pasting where multi-line text gets
+// mangled: "escaped" gives a single JSON string with the newlines escaped,
+// "base64" gives the base64-encoded YAML as plain text
+func writeEncodedYAML(w http.ResponseWriter, yamlOutput string, format string) {
+	switch format {
+	case "escaped":
+		writeJSON(w, http.StatusOK, yamlOutput)
+	case "base64":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, base64.StdEncoding.EncodeToString([]byte(yamlOutput)))
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown format %q (expected escaped or base64)", format)})
+	}
+}
+
 func handleGenerate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -183,7 +832,8 @@ func handleGenerate(w http.ResponseWriter, r *http.Request) {
 		// Parse JSON data for multiple interfaces
 		err = json.NewDecoder(r.Body).Decode(&formData)
 		if err != nil {
-			renderPage(w, formData, "", "Invalid JSON data: "+err.Error())
+			logValidationError(r, err)
+			renderPage(w, formData, "", "Invalid JSON data: "+err.Error(), nil)
 			return
 		}
 	} else {
@@ -192,253 +842,1253 @@ func handleGenerate(w http.ResponseWriter, r *http.Request) {
 			Interfaces: []InterfaceDefinition{{
 				Type:             r.FormValue("interface_type"),
 				Name:             r.FormValue("interface_name"),
+				MACAddress:       r.FormValue("macaddress"),
 				UseStatic:        r.FormValue("use_static") == "on",
+				IPv4Only:         r.FormValue("ipv4_only") == "on",
+				IPv6Only:         r.FormValue("ipv6_only") == "on",
+				DHCP6:            r.FormValue("dhcp6") == "on",
+				Optional:         r.FormValue("optional") == "on",
+				Critical:         r.FormValue("critical") == "on",
+				MTU:              r.FormValue("mtu"),
+				LinkLocal:        r.FormValue("link_local"),
 				Addresses:        r.FormValue("addresses"),
 				Gateway4:         r.FormValue("gateway4"),
 				Gateway6:         r.FormValue("gateway6"),
+				Routes:           r.FormValue("routes"),
+				RoutesCSV:        r.FormValue("routes_csv"),
 				Nameservers:      r.FormValue("nameservers"),
+				Nameservers4:     r.FormValue("nameservers4"),
+				Nameservers6:     r.FormValue("nameservers6"),
 				DHCP4Overrides:   r.FormValue("dhcp4_overrides"),
 				DHCP6Overrides:   r.FormValue("dhcp6_overrides"),
 				BondInterfaces:   r.FormValue("bond_interfaces"),
 				BondMode:         r.FormValue("bond_mode"),
+				BondMIIMonitor:   r.FormValue("bond_mii_monitor_interval"),
+				BondUpDelay:      r.FormValue("bond_up_delay"),
+				BondDownDelay:    r.FormValue("bond_down_delay"),
+				BondMinLinks:     r.FormValue("bond_min_links"),
 				BridgeInterfaces: r.FormValue("bridge_interfaces"),
 			}},
-			Renderer: r.FormValue("renderer"),
+			Renderer:      r.FormValue("renderer"),
+			TargetRelease: r.FormValue("target_release"),
 		}
 	}
 	
 	// Generate netplan configuration
 	config, err := generateNetplanConfig(formData)
 	if err != nil {
+		logValidationError(r, err)
 		if strings.Contains(contentType, "application/json") {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		} else {
-			renderPage(w, formData, "", err.Error())
+			renderPage(w, formData, "", err.Error(), nil)
 		}
 		return
 	}
 	
 	// Convert to YAML
-	yamlOutput := configToYAML(config)
+	yamlOutput := renderYAML(config, formData)
 	
 	if strings.Contains(contentType, "application/json") {
+		if format := r.URL.Query().Get("format"); format != "" {
+			writeEncodedYAML(w, yamlOutput, format)
+			return
+		}
+		response := map[string]interface{}{"yaml": yamlOutput}
+		if len(config.Warnings) > 0 {
+			response["warnings"] = config.Warnings
+		}
+		if savedConfigs != nil {
+			if id, err := savedConfigs.Save(yamlOutput, config); err != nil {
+				log.Printf("Failed to save generated config: %v", err)
+			} else {
+				response["id"] = id
+				response["url"] = "/saved/" + id
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"yaml": yamlOutput})
+		json.NewEncoder(w).Encode(response)
 	} else {
-		renderPage(w, formData, yamlOutput, "")
+		renderPage(w, formData, yamlOutput, "", config.Warnings)
 	}
 }
 
-func renderPage(w http.ResponseWriter, formData FormData, output, errorMsg string) {
-	tmpl, err := template.ParseFS(templateFS, "templates/index.html")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func renderPage(w http.ResponseWriter, formData FormData, output, errorMsg string, warnings []string) {
+	data := PageData{
+		FormData:        formData,
+		Output:          output,
+		Error:           errorMsg,
+		Warnings:        warnings,
+		DefaultBondMode: defaultBondMode,
+	}
+	
+	indexTemplate.Execute(w, data)
+}
+
+// handlePreview renders the generated YAML as a syntax-highlighted HTML
+// fragment that the web UI can embed directly
+func handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	data := PageData{
-		FormData: formData,
-		Output:   output,
-		Error:    errorMsg,
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	
+	var formData FormData
+	if err := json.NewDecoder(r.Body).Decode(&formData); err != nil {
+		logValidationError(r, err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<div class=\"error\">Invalid JSON data: %s</div>", html.EscapeString(err.Error()))
+		return
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		logValidationError(r, err)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<div class=\"error\">%s</div>", html.EscapeString(err.Error()))
+		return
 	}
 	
-	tmpl.Execute(w, data)
+	yamlOutput := renderYAML(config, formData)
+	w.Write([]byte(highlightYAML(yamlOutput)))
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decodeAPIRequest decodes a JSON request body for the /api/v1 endpoints,
+// writing an error response and returning false if it can't
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		logValidationError(r, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON data: " + err.Error()})
+		return false
+	}
+	return true
 }
 
 func generateNetplanConfig(formData FormData) (*NetplanConfig, error) {
+	formData, err := applyProfile(formData)
+	if err != nil {
+		return nil, err
+	}
 	if len(formData.Interfaces) == 0 {
 		return nil, fmt.Errorf("at least one interface is required")
 	}
+	if len(formData.Interfaces) > maxInterfaces {
+		return nil, fmt.Errorf("too many interfaces: %d (at most %d are allowed)", len(formData.Interfaces), maxInterfaces)
+	}
+	if formData.IndentWidth != 0 && formData.IndentWidth != 2 && formData.IndentWidth != 4 {
+		return nil, fmt.Errorf("invalid indent width %d (expected 2 or 4)", formData.IndentWidth)
+	}
+	if formData.NameserverOrder != "" && formData.NameserverOrder != "ipv4-first" && formData.NameserverOrder != "ipv6-first" {
+		return nil, fmt.Errorf("invalid nameserver order %q (expected ipv4-first or ipv6-first)", formData.NameserverOrder)
+	}
+	bondModeDefault, err := normalizeBondMode(formData.BondModeDefault)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bond mode default: %v", err)
+	}
 	
 	config := &NetplanConfig{
 		Network: NetworkConfig{
 			Version:  2,
 			Renderer: formData.Renderer,
 		},
+		IndentWidth:   formData.IndentWidth,
+		MinimalOutput: formData.MinimalOutput,
+		Explicit:      formData.Explicit,
+	}
+	for section, renderer := range formData.SectionRenderers {
+		if !slices.Contains(interfaceSections, section) {
+			return nil, fmt.Errorf("unsupported section %q for a renderer (expected one of %s)", section, strings.Join(interfaceSections, ", "))
+		}
+		if renderer != "networkd" && renderer != "NetworkManager" {
+			return nil, fmt.Errorf("invalid renderer for %s: %q (expected networkd or NetworkManager)", section, renderer)
+		}
+		if config.Network.SectionRenderers == nil {
+			config.Network.SectionRenderers = make(map[string]string)
+		}
+		config.Network.SectionRenderers[section] = renderer
+	}
+	if baseConfig != nil && config.Network.Renderer == "" {
+		config.Network.Renderer = baseConfig.Network.Renderer
+	}
+	if config.Network.Renderer == "" {
+		config.Network.Renderer = detectedRenderer
 	}
 	
-	// Process each interface
+	// Process each interface; disabled ones are left out before any
+	// validation, and only generated on their own if they are to be written
+	// as comments
 	for _, iface := range formData.Interfaces {
 		if iface.Name == "" {
 			return nil, fmt.Errorf("interface name is required")
 		}
-		
-		switch iface.Type {
-		case "ethernet":
-			err := addEthernetToConfig(config, iface)
-			if err != nil {
-				return nil, err
+		if iface.Type == "bond" && iface.BondMode == "" {
+			iface.BondMode = bondModeDefault
+		}
+		if iface.Disabled {
+			if !formData.CommentDisabled {
+				continue
 			}
-		case "bond":
-			err := addBondToConfig(config, iface)
-			if err != nil {
-				return nil, err
+			if config.Disabled == nil {
+				config.Disabled = &NetplanConfig{
+					Network:       NetworkConfig{Version: 2, Renderer: config.Network.Renderer},
+					IndentWidth:   config.IndentWidth,
+					MinimalOutput: config.MinimalOutput,
+					Explicit:      config.Explicit,
+				}
 			}
-		case "bridge":
-			err := addBridgeToConfig(config, iface)
-			if err != nil {
-				return nil, err
+			if err := addInterfaceToConfig(config.Disabled, iface); err != nil {
+				return nil, fmt.Errorf("disabled interface %s: %v", iface.Name, err)
 			}
-		default:
-			return nil, fmt.Errorf("invalid interface type: %s", iface.Type)
+			continue
+		}
+		if err := addInterfaceToConfig(config, iface); err != nil {
+			return nil, err
 		}
 	}
 	
-	return config, nil
-}
-
-func addEthernetToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
-	if config.Network.Ethernets == nil {
-		config.Network.Ethernets = make(map[string]EthernetConfig)
-	}
-	
-	ethConfig := EthernetConfig{}
-	
-	// Set DHCP or static configuration
-	if !iface.UseStatic {
-		dhcp4 := true
-		ethConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		ethConfig.DHCP4 = &dhcp4
-	}
-	
-	// Parse addresses
-	if iface.Addresses != "" {
-		ethConfig.Addresses = parseCommaSeparated(iface.Addresses)
-	}
-	
-	// Set gateways
-	if iface.Gateway4 != "" {
-		ethConfig.Gateway4 = iface.Gateway4
-	}
-	if iface.Gateway6 != "" {
-		ethConfig.Gateway6 = iface.Gateway6
-	}
-	
-	// Parse nameservers
-	if iface.Nameservers != "" {
-		nameservers := parseCommaSeparated(iface.Nameservers)
-		ethConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
-	}
-	
-	// Parse DHCP overrides
-	if iface.DHCP4Overrides != "" {
-		ethConfig.DHCP4Overrides = parseKeyValuePairs(iface.DHCP4Overrides)
+	if formData.IdentifyByMAC {
+		if err := identifyByMAC(config, formData.InterfaceMACs); err != nil {
+			return nil, err
+		}
 	}
-	if iface.DHCP6Overrides != "" {
-		ethConfig.DHCP6Overrides = parseKeyValuePairs(iface.DHCP6Overrides)
+	if err := checkSetNames(config); err != nil {
+		return nil, err
 	}
-	
-	config.Network.Ethernets[iface.Name] = ethConfig
-	return nil
-}
-
-func generateEthernetConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
-	// Legacy function for backward compatibility
-	if len(formData.Interfaces) == 0 {
-		return nil, fmt.Errorf("no interface data provided")
+	if err := checkIfnameLengths(config); err != nil {
+		return nil, err
 	}
-	
-	err := addEthernetToConfig(config, formData.Interfaces[0])
-	if err != nil {
+	if err := checkBondMembers(config); err != nil {
 		return nil, err
 	}
-	
-	return config, nil
-}
-
-func addBondToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
-	if iface.BondInterfaces == "" {
-		return fmt.Errorf("bond interfaces are required for bond %s", iface.Name)
+	if err := checkJumboMembers(config, formData.Interfaces); err != nil {
+		return nil, err
 	}
-	
-	bondInterfaces := parseCommaSeparated(iface.BondInterfaces)
-	
-	// Initialize ethernets map if it doesn't exist
-	if config.Network.Ethernets == nil {
-		config.Network.Ethernets = make(map[string]EthernetConfig)
+	if formData.AllStatic {
+		if formData.MaintenanceInterface != "" {
+			return nil, fmt.Errorf("allStatic can't be combined with maintenance interface %s, which uses DHCP", formData.MaintenanceInterface)
+		}
+		if err := applyAllStatic(config); err != nil {
+			return nil, err
+		}
 	}
-	
-	// Add ethernet declarations for bond interfaces with dhcp4: false
-	for _, ifaceName := range bondInterfaces {
-		dhcp4 := false
-		config.Network.Ethernets[ifaceName] = EthernetConfig{
-			DHCP4: &dhcp4,
+	if formData.MaintenanceInterface != "" {
+		if err := applyMaintenanceInterface(config, formData.MaintenanceInterface); err != nil {
+			return nil, err
 		}
 	}
 	
-	if config.Network.Bonds == nil {
-		config.Network.Bonds = make(map[string]BondConfig)
+	if baseConfig != nil && baseConfig.Network.Nameservers != nil {
+		applyBaseNameservers(config, baseConfig.Network.Nameservers)
 	}
 	
-	bondConfig := BondConfig{
-		Interfaces: bondInterfaces,
-		Parameters: BondParameters{Mode: iface.BondMode},
+	if formData.NameserverOrder != "" {
+		applyNameserverOrder(config, formData.NameserverOrder == "ipv6-first")
 	}
 	
-	// Set DHCP or static configuration
-	if !iface.UseStatic {
-		dhcp4 := true
-		bondConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		bondConfig.DHCP4 = &dhcp4
+	if formData.TargetRelease != "" {
+		if err := normalizeGateways(config, formData.TargetRelease); err != nil {
+			return nil, err
+		}
 	}
 	
-	// Parse addresses
-	if iface.Addresses != "" {
-		bondConfig.Addresses = parseCommaSeparated(iface.Addresses)
-	}
+	checkConfig(config)
 	
-	// Set gateways
-	if iface.Gateway4 != "" {
-		bondConfig.Gateway4 = iface.Gateway4
-	}
-	if iface.Gateway6 != "" {
-		bondConfig.Gateway6 = iface.Gateway6
+	if err := checkSchema(config); err != nil {
+		return nil, err
 	}
-	
-	// Parse nameservers
-	if iface.Nameservers != "" {
-		nameservers := parseCommaSeparated(iface.Nameservers)
-		bondConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
+	if err := checkOutputLint(config); err != nil {
+		return nil, err
 	}
 	
-	config.Network.Bonds[iface.Name] = bondConfig
-	return nil
+	return config, nil
 }
 
-func generateBondConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
-	// Legacy function for backward compatibility
-	if len(formData.Interfaces) == 0 {
-		return nil, fmt.Errorf("no interface data provided")
+// interfaceNamePattern matches the interface names that are written to the
+// YAML as plain scalars; it is a subset of what parseNetplanYAML accepts as a
+// key, so every generated name can be read back
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// addInterfaceToConfig adds one interface definition to the config
+func addInterfaceToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	if len(allowedTypes) > 0 && hasKey(typeHandlers, iface.Type) && !slices.Contains(allowedTypes, iface.Type) {
+		return fmt.Errorf("interface type %s is not allowed for %s (allowed: %s)", iface.Type, iface.Name, strings.Join(allowedTypes, ", "))
+	}
+	if iface.Promiscuous && iface.Type != "ethernet" {
+		return fmt.Errorf("promiscuous mode is only supported for ethernets, not %s %s", iface.Type, iface.Name)
+	}
+	// Names are written unquoted as YAML keys and list items, so anything
+	// outside interfaceNamePattern (":", "#", quotes, flow and anchor
+	// indicators, whitespace) would change the structure of the output
+	names := []string{iface.Name, iface.SetName, strings.TrimSpace(iface.VLANLink)}
+	names = append(names, parseCommaSeparated(iface.BondInterfaces)...)
+	names = append(names, parseCommaSeparated(iface.BridgeInterfaces)...)
+	for _, name := range names {
+		if name != "" && !interfaceNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid interface name %q: only letters, digits, '.', '-' and '_' are allowed, starting with a letter or digit", name)
+		}
 	}
-	
-	err := addBondToConfig(config, formData.Interfaces[0])
-	if err != nil {
-		return nil, err
+	if description := sanitizeDescription(iface.Description); description != "" {
+		if config.Descriptions == nil {
+			config.Descriptions = make(map[string]string)
+		}
+		config.Descriptions[iface.Name] = description
 	}
 	
-	return config, nil
+	add, ok := typeHandlers[iface.Type]
+	if !ok {
+		return fmt.Errorf("invalid interface type: %s", iface.Type)
+	}
+	return add(config, iface)
 }
 
-func addBridgeToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
-	if iface.BridgeInterfaces == "" {
-		return fmt.Errorf("bridge interfaces are required for bridge %s", iface.Name)
+// configChecks are run against every generated config; each one reports
+// problems that don't make the config invalid as warnings
+var configChecks = []func(config *NetplanConfig){
+	checkCriticalOptional,
+	checkAcceptRA,
+	checkOverlappingSubnets,
+	checkLinkLocalAddresses,
+	checkSpecialPurposeAddresses,
+	checkSendHostname,
+	checkReservedNames,
+	checkVLANMTU,
+	checkDefaultRoute,
+	checkNoAddressing,
+}
+
+func checkConfig(config *NetplanConfig) {
+	for _, check := range configChecks {
+		check(config)
 	}
-	
-	bridgeInterfaces := parseCommaSeparated(iface.BridgeInterfaces)
-	
-	// Initialize ethernets map if it doesn't exist
-	if config.Network.Ethernets == nil {
+}
+
+// checkCriticalOptional warns about interfaces marked both critical (never
+// release the DHCP lease) and optional (don't wait for the interface)
+func checkCriticalOptional(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if s.Critical && s.Optional {
+			config.warn("%s: critical and optional are contradictory; critical keeps the interface's lease while optional lets boot continue without it", name)
+		}
+	})
+}
+
+// checkAcceptRA warns about settings that depend on IPv6 router
+// advertisements on interfaces with accept-ra: false
+func checkAcceptRA(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if s.AcceptRA == nil || *s.AcceptRA {
+			return
+		}
+		if s.DHCP6 != nil && *s.DHCP6 {
+			config.warn("%s: dhcp6 is enabled with accept-ra: false; DHCPv6 is normally started by a router advertisement, so no IPv6 address may be obtained", name)
+		}
+		if len(s.DHCP6Overrides) > 0 {
+			config.warn("%s: dhcp6-overrides are set with accept-ra: false; options learned through router advertisements won't apply", name)
+		}
+		if len(s.Addresses) > len(ipv4Addresses(s.Addresses)) && s.Gateway6 == "" && !hasIPv6DefaultRoute(s.Routes) {
+			config.warn("%s: has IPv6 addresses but no IPv6 gateway with accept-ra: false; the default route normally comes from router advertisements", name)
+		}
+	})
+}
+
+// checkOverlappingSubnets warns when addresses on two different interfaces
+// are in overlapping subnets, which leaves the kernel two connected routes
+// to choose between
+func checkOverlappingSubnets(config *NetplanConfig) {
+	type subnet struct {
+		iface   string
+		address string
+		network *net.IPNet
+	}
+	var subnets []subnet
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		for _, addr := range s.Addresses {
+			// Every IPv6 interface has its own fe80::/64, so link-local
+			// addresses never count as overlapping
+			if ip, network, err := net.ParseCIDR(addr); err == nil && !ip.IsLinkLocalUnicast() {
+				subnets = append(subnets, subnet{name, addr, network})
+			}
+		}
+	})
+	
+	warned := make(map[[2]string]bool)
+	for i, a := range subnets {
+		for _, b := range subnets[i+1:] {
+			pair := [2]string{a.iface, b.iface}
+			if a.iface == b.iface || warned[pair] {
+				continue
+			}
+			if a.network.Contains(b.network.IP) || b.network.Contains(a.network.IP) {
+				config.warn("%s and %s have addresses in overlapping subnets (%s and %s), which makes routing between them ambiguous", a.iface, b.iface, a.address, b.address)
+				warned[pair] = true
+			}
+		}
+	}
+}
+
+// checkVLANMTU warns about VLANs with a larger MTU than their link, which
+// the kernel refuses to set; a link without an MTU has the usual 1500
+func checkVLANMTU(config *NetplanConfig) {
+	mtus := make(map[string]int)
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		mtus[name] = s.MTU
+	})
+	for _, name := range sortedKeys(config.Network.VLANs) {
+		vlan := config.Network.VLANs[name]
+		linkMTU, found := mtus[vlan.Link]
+		if vlan.MTU == 0 || !found {
+			continue
+		}
+		if linkMTU == 0 {
+			linkMTU = 1500
+		}
+		if vlan.MTU > linkMTU {
+			config.warn("%s: MTU %d is larger than the MTU %d of its link %s", name, vlan.MTU, linkMTU, vlan.Link)
+		}
+	}
+}
+
+// maxIfnameLength is the kernel's limit on interface names, IFNAMSIZ less
+// the terminating NUL
+const maxIfnameLength = 15
+
+// checkIfnameLength rejects a name longer than maxIfnameLength, or only
+// warns about it when STRICT_IFNAME is off for systems that give
+// interfaces longer alternative names
+func checkIfnameLength(config *NetplanConfig, what, name string) error {
+	if len(name) <= maxIfnameLength {
+		return nil
+	}
+	if strictIfname {
+		return fmt.Errorf("%s %q is longer than %d characters", what, name, maxIfnameLength)
+	}
+	config.warn("%s %q is longer than %d characters, so it only works as an alternative name", what, name, maxIfnameLength)
+	return nil
+}
+
+// reservedNames are interface names the kernel or netplan already use: the
+// loopback device, the "all" and "default" sysctl entries, and the bonding
+// driver's control file
+var reservedNames = map[string]string{
+	"lo":              "the loopback interface",
+	"all":             "reserved for the sysctl settings of all interfaces",
+	"default":         "reserved for the sysctl defaults of new interfaces",
+	"bonding_masters": "reserved by the bonding driver",
+}
+
+// checkReservedNames warns about interfaces using a reserved name, which
+// reconfigures a system interface or can't be created
+func checkReservedNames(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if reason, ok := reservedNames[name]; ok {
+			config.warn("%s: name is %s and shouldn't be configured", name, reason)
+		}
+	})
+}
+
+// checkSendHostname warns about DHCP overrides that set a hostname to send
+// while turning off sending it
+func checkSendHostname(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		for _, overrides := range []struct {
+			key    string
+			values map[string]interface{}
+		}{{"dhcp4-overrides", s.DHCP4Overrides}, {"dhcp6-overrides", s.DHCP6Overrides}} {
+			_, hasHostname := overrides.values["hostname"]
+			if sendHostname, ok := overrides.values["send-hostname"].(bool); ok && !sendHostname && hasHostname {
+				config.warn("%s: %s set a hostname with send-hostname: false, so the hostname is never sent", name, overrides.key)
+			}
+		}
+	})
+}
+
+// checkLinkLocalAddresses warns about static IPv6 link-local (fe80::/10)
+// addresses, which the kernel normally configures by itself
+func checkLinkLocalAddresses(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		for _, addr := range s.Addresses {
+			if ip, _, err := net.ParseCIDR(addr); err == nil && ip.To4() == nil && ip.IsLinkLocalUnicast() {
+				config.warn("%s: %s is an IPv6 link-local address, which is configured automatically and normally shouldn't be set statically", name, addr)
+			}
+		}
+	})
+}
+
+// specialPurposeRanges are address ranges reserved for purposes other than
+// addressing a host on a network (RFC 6890), with what they are for
+var specialPurposeRanges = []struct {
+	prefix  netip.Prefix
+	purpose string
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), "\"this network\""},
+	{netip.MustParsePrefix("127.0.0.0/8"), "loopback"},
+	{netip.MustParsePrefix("192.0.2.0/24"), "documentation"},
+	{netip.MustParsePrefix("198.18.0.0/15"), "benchmarking"},
+	{netip.MustParsePrefix("198.51.100.0/24"), "documentation"},
+	{netip.MustParsePrefix("203.0.113.0/24"), "documentation"},
+	{netip.MustParsePrefix("224.0.0.0/4"), "multicast"},
+	{netip.MustParsePrefix("240.0.0.0/4"), "reserved"},
+	{netip.MustParsePrefix("::1/128"), "loopback"},
+	{netip.MustParsePrefix("2001:db8::/32"), "documentation"},
+	{netip.MustParsePrefix("ff00::/8"), "multicast"},
+}
+
+// checkSpecialPurposeAddresses warns about static addresses in loopback,
+// documentation and other special-purpose ranges, which are usually copied
+// from an example or mistyped
+func checkSpecialPurposeAddresses(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		for _, addr := range s.Addresses {
+			prefix, err := netip.ParsePrefix(addr)
+			if err != nil {
+				continue
+			}
+			for _, r := range specialPurposeRanges {
+				if r.prefix.Contains(prefix.Addr()) {
+					config.warn("%s: %s is in the %s range %s, which is not meant to address a host", name, addr, r.purpose, r.prefix)
+					break
+				}
+			}
+		}
+	})
+}
+
+// checkDefaultRoute warns about static interfaces without a gateway or a
+// default route, which usually means the gateway was forgotten. Interfaces
+// using DHCP get their default route from it, and dummy devices never have
+// one, so they are skipped.
+func checkDefaultRoute(config *NetplanConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if len(s.Addresses) == 0 || hasKey(config.Network.DummyDevices, name) {
+			return
+		}
+		if (s.DHCP4 != nil && *s.DHCP4) || (s.DHCP6 != nil && *s.DHCP6) {
+			return
+		}
+		if s.Gateway4 != "" || s.Gateway6 != "" || hasDefaultRoute(s.Routes) {
+			return
+		}
+		config.warn("%s: has static addresses but no gateway or default route; traffic outside its subnets won't be routed through it", name)
+	})
+}
+
+// checkNoAddressing warns about interfaces that get no address at all:
+// no static addresses, DHCP or router advertisements. Bond and bridge
+// members and VLAN links are meant to carry none, and modems are addressed
+// by ModemManager, so they are skipped.
+func checkNoAddressing(config *NetplanConfig) {
+	carriers := carrierInterfaces(config.Network)
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if carriers[name] || hasKey(config.Network.Modems, name) || len(s.Addresses) > 0 {
+			return
+		}
+		for _, enabled := range []*bool{s.DHCP4, s.DHCP6, s.AcceptRA} {
+			if enabled != nil && *enabled {
+				return
+			}
+		}
+		config.warn("%s: has no static addresses and neither DHCP nor router advertisements enabled, so it gets no IP address", name)
+	})
+}
+
+// carrierInterfaces are the bond and bridge members and VLAN links, which
+// carry the traffic of another interface and need no addressing of their own
+func carrierInterfaces(n NetworkConfig) map[string]bool {
+	carriers := make(map[string]bool)
+	for member := range bondAndBridgeMembers(n) {
+		carriers[member] = true
+	}
+	for _, vlan := range n.VLANs {
+		carriers[vlan.Link] = true
+	}
+	return carriers
+}
+
+// hasDefaultRoute reports whether routes include a default route of either
+// family
+func hasDefaultRoute(routes []Route) bool {
+	for _, route := range routes {
+		if route.To == "default" || route.To == "0.0.0.0/0" || route.To == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIPv4DefaultRoute reports whether the routes include an IPv4 default
+// route, either "default" not via an IPv6 gateway or to 0.0.0.0/0
+func hasIPv4DefaultRoute(routes []Route) bool {
+	for _, route := range routes {
+		if route.To == "0.0.0.0/0" {
+			return true
+		}
+		if route.To == "default" && !strings.Contains(route.Via, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIPv6DefaultRoute reports whether the routes include an IPv6 default
+// route, either "default" via an IPv6 gateway or to ::/0
+func hasIPv6DefaultRoute(routes []Route) bool {
+	for _, route := range routes {
+		if route.To == "::/0" {
+			return true
+		}
+		if route.To == "default" && strings.Contains(route.Via, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBaseNameservers gives the base config's nameservers to every
+// interface with static addresses that has none of its own
+func applyBaseNameservers(config *NetplanConfig, nameservers *NameserversConfig) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if len(s.Addresses) > 0 && s.Nameservers == nil {
+			s.Nameservers = &NameserversConfig{
+				Addresses: append([]string(nil), nameservers.Addresses...),
+				Search:    append([]string(nil), nameservers.Search...),
+			}
+		}
+	})
+}
+
+// applyNameserverOrder moves every interface's IPv4 nameservers before its
+// IPv6 ones, or after them if ipv6First is set; the sort is stable, so each
+// family keeps its input order
+func applyNameserverOrder(config *NetplanConfig, ipv6First bool) {
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if s.Nameservers == nil {
+			return
+		}
+		isIPv6 := func(ns string) bool {
+			ip := net.ParseIP(ns)
+			return ip != nil && ip.To4() == nil
+		}
+		slices.SortStableFunc(s.Nameservers.Addresses, func(a, b string) int {
+			if isIPv6(a) == isIPv6(b) {
+				return 0
+			}
+			if isIPv6(a) != ipv6First {
+				return 1
+			}
+			return -1
+		})
+	})
+}
+
+// normalizeGateways writes every interface's default gateways the way the
+// netplan shipped with the target Ubuntu release and the interface's
+// renderer expect. Releases that deprecate gateway4/gateway6 get default
+// routes instead. Releases that still accept them get them back for
+// NetworkManager interfaces, which take a gateway key as the connection's
+// gateway rather than as an extra route; only a single default route per
+// family without any other route settings is converted.
+func normalizeGateways(config *NetplanConfig, target string) error {
+	capability, ok := releaseCapabilities[target]
+	if !ok {
+		return fmt.Errorf("unsupported target release: %s (supported: %s)", target, strings.Join(sortedKeys(releaseCapabilities), ", "))
+	}
+	
+	var err error
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if !capability.GatewayKeys {
+			for _, family := range []struct {
+				gateway    *string
+				hasDefault func([]Route) bool
+			}{{&s.Gateway4, hasIPv4DefaultRoute}, {&s.Gateway6, hasIPv6DefaultRoute}} {
+				if *family.gateway == "" {
+					continue
+				}
+				// A second default route of the family would conflict
+				// with the one the interface already has
+				if family.hasDefault(s.Routes) {
+					if err == nil {
+						err = fmt.Errorf("%s: gateway %s can't become a default route in Ubuntu %s, as the interface already has one for that address family", name, *family.gateway, target)
+					}
+					continue
+				}
+				s.Routes = append(s.Routes, Route{To: "default", Via: *family.gateway})
+				config.warn("%s: gateway %s converted to a default route (gateway4/gateway6 are deprecated in Ubuntu %s)", name, *family.gateway, target)
+				*family.gateway = ""
+			}
+			return
+		}
+		if config.Network.interfaceRenderer(name) != "NetworkManager" {
+			return
+		}
+		for _, family := range []struct {
+			gateway *string
+			ipv6    bool
+		}{{&s.Gateway4, false}, {&s.Gateway6, true}} {
+			index := -1
+			for i, route := range s.Routes {
+				if route.To != "default" || route.Via == "" || strings.Contains(route.Via, ":") != family.ipv6 {
+					continue
+				}
+				if index != -1 || route != (Route{To: "default", Via: route.Via}) {
+					index = -1
+					break
+				}
+				index = i
+			}
+			if index == -1 || *family.gateway != "" {
+				continue
+			}
+			*family.gateway = s.Routes[index].Via
+			s.Routes = append(s.Routes[:index:index], s.Routes[index+1:]...)
+			config.warn("%s: default route via %s converted to a gateway key, which NetworkManager in Ubuntu %s uses as the connection's gateway", name, *family.gateway, target)
+		}
+		if len(s.Routes) == 0 {
+			s.Routes = nil
+		}
+	})
+	return err
+}
+
+func addEthernetToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	if config.Network.Ethernets == nil {
 		config.Network.Ethernets = make(map[string]EthernetConfig)
 	}
 	
-	// Add ethernet declarations for bridge interfaces with dhcp4: false
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
+	}
+	
+	ethConfig := EthernetConfig{}
+	ethConfig.setSettings(settings)
+	if iface.MatchMAC != "" {
+		if !macAddressPattern.MatchString(iface.MatchMAC) {
+			return fmt.Errorf("invalid match MAC address for %s: %q (expected xx:xx:xx:xx:xx:xx)", iface.Name, iface.MatchMAC)
+		}
+		ethConfig.Match = &MatchConfig{MACAddress: strings.ToLower(iface.MatchMAC)}
+	}
+	if iface.SetName != "" {
+		if ethConfig.Match == nil {
+			return fmt.Errorf("set-name for %s requires a match MAC address", iface.Name)
+		}
+		if strings.ContainsAny(iface.SetName, " \t/:") {
+			return fmt.Errorf("invalid set-name for %s: %q (without spaces, / or :)", iface.Name, iface.SetName)
+		}
+		ethConfig.SetName = iface.SetName
+	}
+	if iface.Promiscuous {
+		ethConfig.Promiscuous = true
+		if config.Network.sectionRenderer("ethernets") == "NetworkManager" {
+			ethConfig.NetworkManager = &NMSettings{Passthrough: map[string]string{promiscuousPassthrough: "1"}}
+		} else {
+			config.warn("%s: netplan can't set promiscuous mode for networkd; it is only included in the networkd export, as Promiscuous=yes", iface.Name)
+		}
+	}
+	
+	config.Network.Ethernets[iface.Name] = ethConfig
+	return nil
+}
+
+// promiscuousPassthrough is the NetworkManager setting that puts an ethernet
+// into promiscuous mode; netplan itself has no key for it
+const promiscuousPassthrough = "ethernet.accept-all-mac-addresses"
+
+// checkIfnameLengths runs checkIfnameLength on the kernel name of every
+// interface: its name, or the set-name of an ethernet with a match, whose
+// name is then only an ID. That includes set-names added by identifyByMAC.
+func checkIfnameLengths(config *NetplanConfig) error {
+	var err error
+	config.forEachInterface(func(name string, _ *interfaceSettings) {
+		if err != nil {
+			return
+		}
+		if eth, ok := config.Network.Ethernets[name]; ok && eth.Match != nil {
+			if eth.SetName != "" {
+				err = checkIfnameLength(config, "set-name for "+name, eth.SetName)
+			}
+			return
+		}
+		err = checkIfnameLength(config, "interface name", name)
+	})
+	return err
+}
+
+// checkSetNames rejects ethernets renamed to the same name, to the name of
+// another ethernet without a match, or to the name of any other interface,
+// which netplan can't both apply
+func checkSetNames(config *NetplanConfig) error {
+	renamedBy := make(map[string]string)
+	for _, name := range sortedKeys(config.Network.Ethernets) {
+		target := config.Network.Ethernets[name].SetName
+		if target == "" {
+			continue
+		}
+		if other, ok := renamedBy[target]; ok {
+			return fmt.Errorf("ethernets %s and %s both set-name to %s", other, name, target)
+		}
+		if other, ok := config.Network.Ethernets[target]; ok && target != name && other.Match == nil {
+			return fmt.Errorf("ethernet %s has set-name %s, which is already the name of another ethernet", name, target)
+		}
+		if kind := virtualKind(config.Network, target); kind != "" {
+			return fmt.Errorf("ethernet %s has set-name %s, which is already the name of a %s", name, target, kind)
+		}
+		renamedBy[target] = name
+	}
+	return nil
+}
+
+// identifyByMAC turns the name of every ethernet without a match of its
+// own, including bond and bridge members, into a match on its MAC address
+// in macs and a set-name to the name. Every such ethernet needs a MAC, and
+// every MAC an ethernet.
+func identifyByMAC(config *NetplanConfig, macs map[string]string) error {
+	for _, name := range sortedKeys(macs) {
+		if !hasKey(config.Network.Ethernets, name) {
+			return fmt.Errorf("MAC address given for %s, which is not an ethernet", name)
+		}
+	}
+	for _, name := range sortedKeys(config.Network.Ethernets) {
+		eth := config.Network.Ethernets[name]
+		if eth.Match != nil {
+			continue
+		}
+		mac, ok := macs[name]
+		if !ok {
+			return fmt.Errorf("no MAC address given for ethernet %s to identify it by", name)
+		}
+		if !macAddressPattern.MatchString(mac) {
+			return fmt.Errorf("invalid MAC address for %s: %q (expected xx:xx:xx:xx:xx:xx)", name, mac)
+		}
+		eth.Match = &MatchConfig{MACAddress: strings.ToLower(mac)}
+		eth.SetName = name
+		config.Network.Ethernets[name] = eth
+	}
+	return nil
+}
+
+// applyMaintenanceInterface makes the named interface reachable whatever
+// else the form says: DHCPv4 is enabled and it isn't optional, so boot
+// waits for it. Each setting overridden to do so is reported as a warning.
+func applyMaintenanceInterface(config *NetplanConfig, name string) error {
+	if hasKey(config.Network.DummyDevices, name) {
+		return fmt.Errorf("maintenance interface %s is a dummy device, which can't use DHCP", name)
+	}
+	if hasKey(config.Network.NMDevices, name) {
+		return fmt.Errorf("maintenance interface %s is an nm-device, whose settings are passed through", name)
+	}
+	if parent, ok := bondAndBridgeMembers(config.Network)[name]; ok {
+		return fmt.Errorf("maintenance interface %s is a member of %s, so it can't have addressing of its own", name, parent)
+	}
+	found := false
+	config.forEachInterface(func(n string, s *interfaceSettings) {
+		if n != name {
+			return
+		}
+		found = true
+		if s.DHCP4 == nil || !*s.DHCP4 {
+			enabled := true
+			s.DHCP4 = &enabled
+			config.warn("%s: dhcp4 enabled, as it is the maintenance interface", name)
+		}
+		if s.Optional {
+			s.Optional = false
+			config.warn("%s: optional removed, as it is the maintenance interface", name)
+		}
+	})
+	if !found {
+		return fmt.Errorf("maintenance interface %s is not defined", name)
+	}
+	return nil
+}
+
+// applyAllStatic turns dhcp4 and dhcp6 off on every interface, failing
+// for one without static addresses. Bond and bridge members and VLAN links
+// need none, as their addressing is on the interface they carry. Modems
+// are rejected, as they get their addresses from the mobile network.
+func applyAllStatic(config *NetplanConfig) error {
+	if names := sortedKeys(config.Network.Modems); len(names) > 0 {
+		return fmt.Errorf("allStatic can't be used with modem %s, which gets its addresses from the mobile network", strings.Join(names, ", "))
+	}
+	carriers := carrierInterfaces(config.Network)
+	
+	var missing []string
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if len(s.Addresses) == 0 && !carriers[name] {
+			missing = append(missing, name)
+		}
+		disabled := false
+		s.DHCP4 = &disabled
+		s.DHCP6 = &disabled
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("allStatic requires static addresses, which %s lacks", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// bondAndBridgeMembers maps the members of every bond and bridge to the
+// bond or bridge they are in
+func bondAndBridgeMembers(n NetworkConfig) map[string]string {
+	memberOf := make(map[string]string)
+	for _, name := range sortedKeys(n.Bonds) {
+		for _, member := range n.Bonds[name].Interfaces {
+			memberOf[member] = name
+		}
+	}
+	for _, name := range sortedKeys(n.Bridges) {
+		for _, member := range n.Bridges[name].Interfaces {
+			memberOf[member] = name
+		}
+	}
+	return memberOf
+}
+
+// virtualKind describes what the named interface is defined as, if it is
+// anything but an ethernet
+func virtualKind(n NetworkConfig, name string) string {
+	switch {
+	case hasKey(n.Bonds, name):
+		return "bond"
+	case hasKey(n.Bridges, name):
+		return "bridge"
+	case hasKey(n.VLANs, name):
+		return "VLAN"
+	case hasKey(n.DummyDevices, name):
+		return "dummy device"
+	case hasKey(n.Modems, name):
+		return "modem"
+	case hasKey(n.NMDevices, name):
+		return "nm-device"
+	}
+	return ""
+}
+
+// checkBondMembers rejects bonds with a member that is defined as anything
+// but an ethernet or a VLAN. It runs once every interface is added, as the
+// member may come after the bond; a VLAN member replaces the ethernet
+// declaration the bond added for it.
+func checkBondMembers(config *NetplanConfig) error {
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		for _, member := range config.Network.Bonds[name].Interfaces {
+			switch kind := virtualKind(config.Network, member); kind {
+			case "":
+			case "VLAN":
+				delete(config.Network.Ethernets, member)
+			default:
+				return fmt.Errorf("bond %s member %s is a %s; bond members must be ethernets or VLANs", name, member, kind)
+			}
+		}
+	}
+	return nil
+}
+
+// addDummyToConfig adds a dummy device; dummies have no link to run DHCP
+// on, so their addresses are always static and at least one is required
+func addDummyToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	if config.Network.DummyDevices == nil {
+		config.Network.DummyDevices = make(map[string]EthernetConfig)
+	}
+	
+	if iface.DHCP4 != nil && *iface.DHCP4 {
+		return fmt.Errorf("dummy device %s can't use DHCP", iface.Name)
+	}
+	iface.UseStatic = true
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
+	}
+	if len(settings.Addresses) == 0 {
+		return fmt.Errorf("dummy device %s requires at least one address", iface.Name)
+	}
+	
+	dummyConfig := EthernetConfig{}
+	dummyConfig.setSettings(settings)
+	
+	config.Network.DummyDevices[iface.Name] = dummyConfig
+	return nil
+}
+
+// addModemToConfig adds a GSM/CDMA modem; modems are brought up through
+// ModemManager, which only the NetworkManager renderer talks to
+func addModemToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	if !iface.ModemAutoConfig && iface.ModemAPN == "" {
+		return fmt.Errorf("modem %s requires an APN or auto-config", iface.Name)
+	}
+	if iface.ModemPIN != "" {
+		if _, err := strconv.Atoi(iface.ModemPIN); err != nil {
+			return fmt.Errorf("invalid pin for modem %s: must be digits only", iface.Name)
+		}
+	}
+	
+	if config.Network.Modems == nil {
+		config.Network.Modems = make(map[string]ModemConfig)
+	}
+	
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
+	}
+	
+	modemConfig := ModemConfig{
+		APN:        iface.ModemAPN,
+		AutoConfig: iface.ModemAutoConfig,
+		Number:     iface.ModemNumber,
+		PIN:        iface.ModemPIN,
+	}
+	modemConfig.setSettings(settings)
+	
+	if config.Network.sectionRenderer("modems") != "NetworkManager" {
+		config.warn("%s: modems are only supported by the NetworkManager renderer", iface.Name)
+	}
+	
+	config.Network.Modems[iface.Name] = modemConfig
+	return nil
+}
+
+// addNMDeviceToConfig adds an nm-devices entry whose passthrough settings
+// are given one "section.key=value" per line
+func addNMDeviceToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	passthrough, err := parsePassthrough(iface.NMPassthrough)
+	if err != nil {
+		return fmt.Errorf("invalid passthrough for %s: %v", iface.Name, err)
+	}
+	if len(passthrough) == 0 {
+		return fmt.Errorf("nm-device %s requires at least one passthrough setting", iface.Name)
+	}
+	
+	if config.Network.NMDevices == nil {
+		config.Network.NMDevices = make(map[string]NMDeviceConfig)
+	}
+	
+	if config.Network.sectionRenderer("nm-devices") != "NetworkManager" {
+		config.warn("%s: nm-devices are only used by the NetworkManager renderer", iface.Name)
+	}
+	
+	config.Network.NMDevices[iface.Name] = NMDeviceConfig{
+		NetworkManager: NMSettings{Passthrough: passthrough},
+	}
+	return nil
+}
+
+// parsePassthrough parses NetworkManager settings, one "section.key=value"
+// per line, e.g. "connection.type=wireguard"
+func parsePassthrough(input string) (map[string]string, error) {
+	result := make(map[string]string)
+	
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !strings.Contains(key, ".") || !yamlKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected \"section.key=value\", got %q", i+1, line)
+		}
+		if _, exists := result[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate setting %s", i+1, key)
+		}
+		result[key] = strings.TrimSpace(value)
+	}
+	
+	return result, nil
+}
+
+func generateEthernetConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
+	// Legacy function for backward compatibility
+	if len(formData.Interfaces) == 0 {
+		return nil, fmt.Errorf("no interface data provided")
+	}
+	
+	err := addEthernetToConfig(config, formData.Interfaces[0])
+	if err != nil {
+		return nil, err
+	}
+	
+	return config, nil
+}
+
+func addBondToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	if iface.BondInterfaces == "" {
+		return fmt.Errorf("bond interfaces are required for bond %s", iface.Name)
+	}
+	
+	bondInterfaces := parseCommaSeparated(iface.BondInterfaces)
+	
+	// Initialize ethernets map if it doesn't exist
+	if config.Network.Ethernets == nil {
+		config.Network.Ethernets = make(map[string]EthernetConfig)
+	}
+	
+	// Jumbo frames only work if the bond and every member agree on the MTU
+	if iface.JumboFrames {
+		if iface.MTU != "" {
+			if mtu, err := parseIntValue(iface.MTU, mtuUnits); err != nil || mtu != jumboMTU {
+				return fmt.Errorf("bond %s has jumbo frames, which set the MTU to %d, but MTU %s is given", iface.Name, jumboMTU, iface.MTU)
+			}
+		}
+		iface.MTU = strconv.Itoa(jumboMTU)
+	}
+	
+	// Add ethernet declarations for bond interfaces
+	for _, ifaceName := range bondInterfaces {
+		stub := memberStub(config, iface)
+		if iface.JumboFrames {
+			if mtu := config.Network.Ethernets[ifaceName].MTU; mtu != 0 && mtu != jumboMTU {
+				return jumboMemberError(iface.Name, ifaceName, mtu)
+			}
+			stub.MTU = jumboMTU
+		}
+		config.Network.Ethernets[ifaceName] = stub
+	}
+	
+	if config.Network.Bonds == nil {
+		config.Network.Bonds = make(map[string]BondConfig)
+	}
+	
+	mode, err := normalizeBondMode(iface.BondMode)
+	if err != nil {
+		return fmt.Errorf("invalid mode for bond %s: %v", iface.Name, err)
+	}
+	if mode == "" {
+		mode = defaultBondMode
+	}
+	
+	bondConfig := BondConfig{
+		Interfaces: bondInterfaces,
+		Parameters: BondParameters{
+			Mode:             mode,
+			AllMembersActive: iface.BondAllActive,
+		},
+	}
+	
+	// Parse bond timing and count parameters
+	bondParameters := []struct {
+		key    string
+		input  string
+		units  map[string]int
+		target *int
+	}{
+		{"mii-monitor-interval", iface.BondMIIMonitor, intervalUnits, &bondConfig.Parameters.MIIMonitorInterval},
+		{"up-delay", iface.BondUpDelay, intervalUnits, &bondConfig.Parameters.UpDelay},
+		{"down-delay", iface.BondDownDelay, intervalUnits, &bondConfig.Parameters.DownDelay},
+		{"min-links", iface.BondMinLinks, countUnits, &bondConfig.Parameters.MinLinks},
+	}
+	for _, param := range bondParameters {
+		if param.input == "" {
+			continue
+		}
+		value, err := parseIntValue(param.input, param.units)
+		if err != nil {
+			return fmt.Errorf("invalid %s for bond %s: %v", param.key, iface.Name, err)
+		}
+		*param.target = value
+	}
+	
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
+	}
+	bondConfig.setSettings(settings)
+	
+	config.Network.Bonds[iface.Name] = bondConfig
+	return nil
+}
+
+// checkJumboMembers checks the members of bonds with jumbo frames that are
+// defined after the bond, and so replace its member stubs: a member without
+// an MTU gets the jumbo MTU, and one with another MTU is rejected
+func checkJumboMembers(config *NetplanConfig, interfaces []InterfaceDefinition) error {
+	for _, iface := range interfaces {
+		if iface.Type != "bond" || !iface.JumboFrames || iface.Disabled {
+			continue
+		}
+		for _, member := range parseCommaSeparated(iface.BondInterfaces) {
+			eth, ok := config.Network.Ethernets[member]
+			if !ok {
+				continue
+			}
+			switch eth.MTU {
+			case jumboMTU:
+			case 0:
+				eth.MTU = jumboMTU
+				config.Network.Ethernets[member] = eth
+			default:
+				return jumboMemberError(iface.Name, member, eth.MTU)
+			}
+		}
+	}
+	return nil
+}
+
+func jumboMemberError(bond, member string, mtu int) error {
+	return fmt.Errorf("bond %s has jumbo frames, which set the MTU to %d, but its member %s has MTU %d", bond, jumboMTU, member, mtu)
+}
+
+// memberStub returns the ethernet declaration added for a bond or bridge
+// member. Under networkd, dhcp4 and dhcp6 are disabled so the member
+// doesn't pick up addresses of its own, unless the parent allows IPv6 on
+// its members. NetworkManager never configures IP on a port, so there the
+// member is only declared.
+func memberStub(config *NetplanConfig, parent InterfaceDefinition) EthernetConfig {
+	if config.Network.sectionRenderer("ethernets") == "NetworkManager" {
+		return EthernetConfig{}
+	}
+	dhcp4 := false
+	stub := EthernetConfig{DHCP4: &dhcp4}
+	if !parent.AllowMemberIPv6 {
+		dhcp6 := false
+		stub.DHCP6 = &dhcp6
+	}
+	return stub
+}
+
+func generateBondConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
+	// Legacy function for backward compatibility
+	if len(formData.Interfaces) == 0 {
+		return nil, fmt.Errorf("no interface data provided")
+	}
+	
+	err := addBondToConfig(config, formData.Interfaces[0])
+	if err != nil {
+		return nil, err
+	}
+	
+	return config, nil
+}
+
+func addBridgeToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	// A bridge without members is only useful as a host-only bridge, which
+	// needs addresses of its own
+	bridgeInterfaces := parseCommaSeparated(iface.BridgeInterfaces)
+	if len(bridgeInterfaces) == 0 {
+		if len(parseCommaSeparated(iface.Addresses)) == 0 {
+			return fmt.Errorf("bridge %s needs member interfaces, or addresses if it is a host-only bridge", iface.Name)
+		}
+		bridgeInterfaces = nil
+	}
+	
+	// Initialize ethernets map if it doesn't exist
+	if config.Network.Ethernets == nil {
+		config.Network.Ethernets = make(map[string]EthernetConfig)
+	}
+	
+	// Add ethernet declarations for bridge interfaces
 	// But only if they're not already defined (could be bonds)
 	for _, ifaceName := range bridgeInterfaces {
 		// Check if this interface is already defined as a bond
@@ -451,10 +2101,7 @@ func addBridgeToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
 		
 		// Check if this interface is already defined as an ethernet
 		if _, exists := config.Network.Ethernets[ifaceName]; !exists {
-			dhcp4 := false
-			config.Network.Ethernets[ifaceName] = EthernetConfig{
-				DHCP4: &dhcp4,
-			}
+			config.Network.Ethernets[ifaceName] = memberStub(config, iface)
 		}
 	}
 	
@@ -466,36 +2113,44 @@ func addBridgeToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
 		Interfaces: bridgeInterfaces,
 	}
 	
-	// Set DHCP or static configuration
-	if !iface.UseStatic {
-		dhcp4 := true
-		bridgeConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		bridgeConfig.DHCP4 = &dhcp4
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
 	}
+	bridgeConfig.setSettings(settings)
 	
-	// Parse addresses
-	if iface.Addresses != "" {
-		bridgeConfig.Addresses = parseCommaSeparated(iface.Addresses)
+	config.Network.Bridges[iface.Name] = bridgeConfig
+	return nil
+}
+
+// addVLANToConfig adds a VLAN on top of its link interface, which has to
+// be defined in the config as well
+func addVLANToConfig(config *NetplanConfig, iface InterfaceDefinition) error {
+	id, err := strconv.Atoi(strings.TrimSpace(iface.VLANID))
+	if err != nil || id < 1 || id > 4094 {
+		return fmt.Errorf("invalid VLAN ID for %s: %q (expected 1-4094)", iface.Name, iface.VLANID)
+	}
+	link := strings.TrimSpace(iface.VLANLink)
+	if link == "" {
+		return fmt.Errorf("VLAN %s requires a link interface", iface.Name)
 	}
 	
-	// Set gateways
-	if iface.Gateway4 != "" {
-		bridgeConfig.Gateway4 = iface.Gateway4
+	if config.Network.VLANs == nil {
+		config.Network.VLANs = make(map[string]VLANConfig)
 	}
-	if iface.Gateway6 != "" {
-		bridgeConfig.Gateway6 = iface.Gateway6
+	
+	vlanConfig := VLANConfig{
+		ID:   id,
+		Link: link,
 	}
 	
-	// Parse nameservers
-	if iface.Nameservers != "" {
-		nameservers := parseCommaSeparated(iface.Nameservers)
-		bridgeConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
+	settings, err := parseInterfaceSettings(iface)
+	if err != nil {
+		return err
 	}
+	vlanConfig.setSettings(settings)
 	
-	config.Network.Bridges[iface.Name] = bridgeConfig
+	config.Network.VLANs[iface.Name] = vlanConfig
 	return nil
 }
 
@@ -512,168 +2167,411 @@ func generateBridgeConfig(config *NetplanConfig, formData FormData) (*NetplanCon
 	
 	return config, nil
 }
-	config.Network.Ethernets = make(map[string]EthernetConfig)
+
+// parseInterfaceSettings converts the inputs shared by every interface type
+// into the settings written under the interface
+func parseInterfaceSettings(iface InterfaceDefinition) (interfaceSettings, error) {
+	settings := interfaceSettings{}
+	
+	// Set MAC address, either a literal MAC or one of netplan's keywords
+	if iface.MACAddress != "" {
+		if err := validateMACAddress(iface.MACAddress); err != nil {
+			return settings, fmt.Errorf("invalid macaddress for %s: %v", iface.Name, err)
+		}
+		settings.MACAddress = iface.MACAddress
+	}
 	
-	ethConfig := EthernetConfig{}
+	settings.Optional = iface.Optional
+	settings.Critical = iface.Critical
 	
-	// Set DHCP or static configuration
-	if !formData.UseStatic {
-		dhcp4 := true
-		ethConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		ethConfig.DHCP4 = &dhcp4
+	// Parse MTU
+	if iface.MTU != "" {
+		mtu, err := parseIntValue(iface.MTU, mtuUnits)
+		if err != nil {
+			return settings, fmt.Errorf("invalid mtu for %s: %v", iface.Name, err)
+		}
+		if mtu < minMTU || mtu > maxMTU {
+			return settings, fmt.Errorf("invalid mtu for %s: %d is outside %d-%d", iface.Name, mtu, minMTU, maxMTU)
+		}
+		settings.MTU = mtu
+	}
+	
+	// Set IPv6 router advertisement and link-local handling
+	settings.AcceptRA = iface.AcceptRA
+	if iface.LinkLocal != "" {
+		settings.LinkLocal = parseCommaSeparated(iface.LinkLocal)
+		for _, family := range settings.LinkLocal {
+			if family != "ipv4" && family != "ipv6" {
+				return settings, fmt.Errorf("invalid link-local value for %s: %s (expected ipv4 or ipv6)", iface.Name, family)
+			}
+		}
 	}
 	
 	// Parse addresses
-	if formData.Addresses != "" {
-		ethConfig.Addresses = parseCommaSeparated(formData.Addresses)
+	if iface.Addresses != "" {
+		settings.Addresses = parseCommaSeparated(iface.Addresses)
 	}
 	
-	// Set gateways
-	if formData.Gateway4 != "" {
-		ethConfig.Gateway4 = formData.Gateway4
+	// Set DHCP or static configuration. dhcp4 is only written when the
+	// interface has some IPv4 intent, so IPv6-only interfaces don't get a
+	// spurious dhcp4 line
+	if iface.IPv6Only && iface.IPv4Only {
+		return settings, fmt.Errorf("%s cannot be both IPv4 only and IPv6 only", iface.Name)
 	}
-	if formData.Gateway6 != "" {
-		ethConfig.Gateway6 = formData.Gateway6
+	if hasIPv4Intent(iface, settings.Addresses) {
+		// When static is selected, explicitly set dhcp4: false
+		dhcp4 := !iface.UseStatic
+		settings.DHCP4 = &dhcp4
+	} else if iface.IPv6Only && (iface.Gateway4 != "" || iface.Nameservers4 != "" || len(ipv4Addresses(settings.Addresses)) > 0) {
+		return settings, fmt.Errorf("%s is IPv6 only but has IPv4 addresses, gateway or nameservers", iface.Name)
+	}
+	// An explicit dhcp4 overrides the one derived from static, so DHCP can
+	// run alongside static addresses
+	if iface.DHCP4 != nil {
+		if *iface.DHCP4 && iface.IPv6Only {
+			return settings, fmt.Errorf("%s is IPv6 only but has dhcp4 enabled", iface.Name)
+		}
+		dhcp4 := *iface.DHCP4
+		settings.DHCP4 = &dhcp4
 	}
-	
-	// Parse nameservers
-	if formData.Nameservers != "" {
-		nameservers := parseCommaSeparated(formData.Nameservers)
-		ethConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
+	if iface.DHCP6 || (iface.IPv6Only && !iface.UseStatic) {
+		dhcp6 := true
+		settings.DHCP6 = &dhcp6
 	}
 	
-	// Parse DHCP overrides
-	if formData.DHCP4Overrides != "" {
-		ethConfig.DHCP4Overrides = parseKeyValuePairs(formData.DHCP4Overrides)
+	// Set gateways, each of which must be of its own address family
+	if iface.Gateway4 != "" {
+		if ip := net.ParseIP(iface.Gateway4); ip == nil || ip.To4() == nil {
+			return settings, fmt.Errorf("invalid gateway4 for %s: %s is not an IPv4 address", iface.Name, iface.Gateway4)
+		}
+		settings.Gateway4 = iface.Gateway4
 	}
-	if formData.DHCP6Overrides != "" {
-		ethConfig.DHCP6Overrides = parseKeyValuePairs(formData.DHCP6Overrides)
+	if iface.Gateway6 != "" {
+		if ip := net.ParseIP(iface.Gateway6); ip == nil || ip.To4() != nil {
+			return settings, fmt.Errorf("invalid gateway6 for %s: %s is not an IPv6 address", iface.Name, iface.Gateway6)
+		}
+		settings.Gateway6 = iface.Gateway6
 	}
 	
-	config.Network.Ethernets[formData.InterfaceName] = ethConfig
-	return config, nil
-}
-
-func generateBondConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
-	if formData.BondInterfaces == "" {
-		return nil, fmt.Errorf("bond interfaces are required")
+	// Parse routes, one "to via [metric]" per line and/or CSV with a header row
+	if iface.Routes != "" {
+		routes, err := parseRoutes(iface.Routes)
+		if err != nil {
+			return settings, fmt.Errorf("invalid routes for %s: %v", iface.Name, err)
+		}
+		settings.Routes = append(settings.Routes, routes...)
+	}
+	if iface.RoutesCSV != "" {
+		routes, err := parseRoutesCSV(iface.RoutesCSV)
+		if err != nil {
+			return settings, fmt.Errorf("invalid routes CSV for %s: %v", iface.Name, err)
+		}
+		settings.Routes = append(settings.Routes, routes...)
+	}
+	if len(settings.Routes) > 0 {
+		routes, err := dedupeRoutes(settings.Routes)
+		if err != nil {
+			return settings, fmt.Errorf("invalid routes for %s: %v", iface.Name, err)
+		}
+		settings.Routes = routes
 	}
 	
-	bondInterfaces := parseCommaSeparated(formData.BondInterfaces)
-	
-	// Initialize ethernets map if it doesn't exist
-	if config.Network.Ethernets == nil {
-		config.Network.Ethernets = make(map[string]EthernetConfig)
+	// Parse nameservers; the per-family inputs are validated and appended
+	// after the combined input
+	nameservers := parseCommaSeparated(iface.Nameservers)
+	for _, ns := range parseCommaSeparated(iface.Nameservers4) {
+		if ip := net.ParseIP(ns); ip == nil || ip.To4() == nil {
+			return settings, fmt.Errorf("invalid IPv4 nameserver for %s: %s", iface.Name, ns)
+		}
+		nameservers = append(nameservers, ns)
+	}
+	for _, ns := range parseCommaSeparated(iface.Nameservers6) {
+		if ip := net.ParseIP(ns); ip == nil || ip.To4() != nil {
+			return settings, fmt.Errorf("invalid IPv6 nameserver for %s: %s", iface.Name, ns)
+		}
+		nameservers = append(nameservers, ns)
+	}
+	searchDomains := parseCommaSeparated(iface.SearchDomains)
+	for _, domain := range searchDomains {
+		if len(domain) > 253 || !searchDomainPattern.MatchString(domain) {
+			return settings, fmt.Errorf("invalid search domain for %s: %q", iface.Name, domain)
+		}
+	}
+	if len(nameservers) == 0 {
+		nameservers = nil
+	}
+	if len(searchDomains) == 0 {
+		searchDomains = nil
+	}
+	if iface.ClearNameservers {
+		if nameservers != nil {
+			return settings, fmt.Errorf("nameservers for %s can't be both cleared and given", iface.Name)
+		}
+		nameservers = []string{}
+	}
+	if nameservers != nil || searchDomains != nil {
+		settings.Nameservers = &NameserversConfig{Addresses: nameservers, Search: searchDomains}
 	}
 	
-	// Add ethernet declarations for bond interfaces with dhcp4: false
-	for _, iface := range bondInterfaces {
-		dhcp4 := false
-		config.Network.Ethernets[iface] = EthernetConfig{
-			DHCP4: &dhcp4,
+	// Parse DHCP overrides; the dedicated use-dns and route-metric inputs
+	// are merged into the free-form ones
+	var err error
+	if iface.DHCP4Overrides != "" {
+		settings.DHCP4Overrides, err = normalizeOverrideKeys(parseKeyValuePairs(iface.DHCP4Overrides))
+		if err != nil {
+			return settings, fmt.Errorf("invalid DHCPv4 overrides for %s: %v", iface.Name, err)
 		}
 	}
+	if iface.DHCP6Overrides != "" {
+		settings.DHCP6Overrides, err = normalizeOverrideKeys(parseKeyValuePairs(iface.DHCP6Overrides))
+		if err != nil {
+			return settings, fmt.Errorf("invalid DHCPv6 overrides for %s: %v", iface.Name, err)
+		}
+	}
+	settings.DHCP4Overrides, err = mergeDHCPOverrides(settings.DHCP4Overrides, iface.DHCP4UseDNS, iface.DHCP4RouteMetric)
+	if err != nil {
+		return settings, fmt.Errorf("invalid DHCPv4 overrides for %s: %v", iface.Name, err)
+	}
+	settings.DHCP6Overrides, err = mergeDHCPOverrides(settings.DHCP6Overrides, iface.DHCP6UseDNS, iface.DHCP6RouteMetric)
+	if err != nil {
+		return settings, fmt.Errorf("invalid DHCPv6 overrides for %s: %v", iface.Name, err)
+	}
 	
-	config.Network.Bonds = make(map[string]BondConfig)
+	if iface.IPv4Only {
+		applyIPv4Only(&settings)
+	}
+	if iface.IPv6Only {
+		if err := checkIPv6Only(settings); err != nil {
+			return settings, fmt.Errorf("%s is IPv6 only but %v", iface.Name, err)
+		}
+	}
 	
-	bondConfig := BondConfig{
-		Interfaces: bondInterfaces,
-		Parameters: BondParameters{Mode: formData.BondMode},
+	return settings, nil
+}
+
+// dhcpOverrideKeys are the keys netplan accepts in dhcp4-overrides and
+// dhcp6-overrides
+var dhcpOverrideKeys = []string{
+	"use-dns", "use-ntp", "send-hostname", "use-hostname", "use-mtu",
+	"hostname", "use-routes", "route-metric", "use-domains",
+}
+
+// normalizeOverrideKeys rewrites keys that are one of dhcpOverrideKeys in
+// another casing, such as use_dns or useDns, to the kebab-case netplan
+// expects. Other keys are kept as they are.
+func normalizeOverrideKeys(overrides map[string]interface{}) (map[string]interface{}, error) {
+	compact := func(key string) string {
+		return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	}
+	normalized := make(map[string]interface{}, len(overrides))
+	for _, key := range sortedKeys(overrides) {
+		canonical := key
+		for _, known := range dhcpOverrideKeys {
+			if compact(key) == compact(known) {
+				canonical = known
+				break
+			}
+		}
+		if existing, ok := normalized[canonical]; ok && existing != overrides[key] {
+			return nil, fmt.Errorf("%s is set to both %v and %v", canonical, existing, overrides[key])
+		}
+		normalized[canonical] = overrides[key]
+	}
+	return normalized, nil
+}
+
+// mergeDHCPOverrides adds the use-dns and route-metric inputs to the
+// overrides parsed from the free-form input, which may set the same keys
+// only to the same values
+func mergeDHCPOverrides(overrides map[string]interface{}, useDNS *bool, routeMetric string) (map[string]interface{}, error) {
+	set := func(key string, value interface{}) error {
+		if existing, ok := overrides[key]; ok && existing != value {
+			return fmt.Errorf("%s is set to both %v and %v", key, existing, value)
+		}
+		if overrides == nil {
+			overrides = make(map[string]interface{})
+		}
+		overrides[key] = value
+		return nil
 	}
 	
-	// Set DHCP or static configuration
-	if !formData.UseStatic {
-		dhcp4 := true
-		bondConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		bondConfig.DHCP4 = &dhcp4
+	if useDNS != nil {
+		if err := set("use-dns", *useDNS); err != nil {
+			return overrides, err
+		}
 	}
-	
-	// Parse addresses
-	if formData.Addresses != "" {
-		bondConfig.Addresses = parseCommaSeparated(formData.Addresses)
+	if routeMetric != "" {
+		metric, err := strconv.Atoi(routeMetric)
+		if err != nil || metric < 0 {
+			return overrides, fmt.Errorf("invalid route metric %s", routeMetric)
+		}
+		if err := set("route-metric", metric); err != nil {
+			return overrides, err
+		}
 	}
-	
-	// Set gateways
-	if formData.Gateway4 != "" {
-		bondConfig.Gateway4 = formData.Gateway4
+	return overrides, nil
+}
+
+// searchDomainPattern matches a DNS domain name: dot-separated labels of
+// letters, digits and inner hyphens, with an optional trailing dot
+var searchDomainPattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+
+// hasIPv4Intent reports whether the user asked for IPv4 on the interface:
+// always with DHCP unless IPv6 only is set, and with static configuration
+// unless every address is IPv6 and there is no IPv4 gateway
+func hasIPv4Intent(iface InterfaceDefinition, addresses []string) bool {
+	if iface.IPv6Only {
+		return false
 	}
-	if formData.Gateway6 != "" {
-		bondConfig.Gateway6 = formData.Gateway6
+	if !iface.UseStatic || len(addresses) == 0 || iface.Gateway4 != "" {
+		return true
 	}
-	
-	// Parse nameservers
-	if formData.Nameservers != "" {
-		nameservers := parseCommaSeparated(formData.Nameservers)
-		bondConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
+	return len(ipv4Addresses(addresses)) > 0
+}
+
+// ipv4Addresses returns the addresses, with or without prefix length, that
+// are IPv4; anything unparseable is treated as IPv4
+func ipv4Addresses(addresses []string) []string {
+	var result []string
+	for _, addr := range addresses {
+		ip, _, err := net.ParseCIDR(addr)
+		if err != nil {
+			ip = net.ParseIP(addr)
+		}
+		if ip == nil || ip.To4() != nil {
+			result = append(result, addr)
+		}
 	}
-	
-	config.Network.Bonds[formData.InterfaceName] = bondConfig
-	return config, nil
+	return result
 }
 
-func generateBridgeConfig(config *NetplanConfig, formData FormData) (*NetplanConfig, error) {
-	if formData.BridgeInterfaces == "" {
-		return nil, fmt.Errorf("bridge interfaces are required")
+// applyIPv4Only disables every source of IPv6 configuration on the
+// interface, leaving any setting the user chose explicitly untouched
+func applyIPv4Only(settings *interfaceSettings) {
+	if settings.DHCP6 == nil {
+		dhcp6 := false
+		settings.DHCP6 = &dhcp6
 	}
-	
-	bridgeInterfaces := parseCommaSeparated(formData.BridgeInterfaces)
-	
-	// Initialize ethernets map if it doesn't exist
-	if config.Network.Ethernets == nil {
-		config.Network.Ethernets = make(map[string]EthernetConfig)
+	if settings.AcceptRA == nil {
+		acceptRA := false
+		settings.AcceptRA = &acceptRA
 	}
-	
-	// Add ethernet declarations for bridge interfaces with dhcp4: false
-	for _, iface := range bridgeInterfaces {
-		dhcp4 := false
-		config.Network.Ethernets[iface] = EthernetConfig{
-			DHCP4: &dhcp4,
+	if settings.LinkLocal == nil {
+		settings.LinkLocal = []string{"ipv4"}
+	}
+}
+
+// checkIPv6Only rejects the IPv4 nameservers and routes the family-neutral
+// inputs can give an IPv6-only interface, so its config has no IPv4 at all;
+// addresses and gateways are checked as they are parsed
+func checkIPv6Only(settings interfaceSettings) error {
+	if settings.Nameservers != nil {
+		if v4 := ipv4Addresses(settings.Nameservers.Addresses); len(v4) > 0 {
+			return fmt.Errorf("has IPv4 nameservers: %s", strings.Join(v4, ", "))
 		}
 	}
-	
-	config.Network.Bridges = make(map[string]BridgeConfig)
-	
-	bridgeConfig := BridgeConfig{
-		Interfaces: bridgeInterfaces,
+	for _, route := range settings.Routes {
+		to := []string{route.To}
+		if route.To == "default" {
+			to = nil
+		}
+		if len(ipv4Addresses(to)) > 0 || (route.Via != "" && len(ipv4Addresses([]string{route.Via})) > 0) {
+			return fmt.Errorf("has an IPv4 route to %s", route.To)
+		}
 	}
-	
-	// Set DHCP or static configuration
-	if !formData.UseStatic {
-		dhcp4 := true
-		bridgeConfig.DHCP4 = &dhcp4
-	} else {
-		// When static is selected, explicitly set dhcp4: false
-		dhcp4 := false
-		bridgeConfig.DHCP4 = &dhcp4
+	return nil
+}
+
+const (
+	minMTU   = 68
+	maxMTU   = 65535
+	jumboMTU = 9000
+)
+
+// bondModes are the bonding modes netplan accepts
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+// bondModeAliases map other common names for the bonding modes, after
+// lowercasing and replacing underscores with hyphens, to netplan's names.
+// The kernel's numeric modes are accepted too.
+var bondModeAliases = map[string]string{
+	"0":            "balance-rr",
+	"roundrobin":   "balance-rr",
+	"round-robin":  "balance-rr",
+	"rr":           "balance-rr",
+	"1":            "active-backup",
+	"activebackup": "active-backup",
+	"failover":     "active-backup",
+	"2":            "balance-xor",
+	"xor":          "balance-xor",
+	"3":            "broadcast",
+	"4":            "802.3ad",
+	"lacp":         "802.3ad",
+	"8023ad":       "802.3ad",
+	"5":            "balance-tlb",
+	"tlb":          "balance-tlb",
+	"6":            "balance-alb",
+	"alb":          "balance-alb",
+}
+
+// normalizeBondMode maps a bonding mode or one of its aliases to netplan's
+// name for it and rejects anything else. An empty mode is left empty.
+func normalizeBondMode(mode string) (string, error) {
+	if mode == "" {
+		return "", nil
 	}
-	
-	// Parse addresses
-	if formData.Addresses != "" {
-		bridgeConfig.Addresses = parseCommaSeparated(formData.Addresses)
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(mode)), "_", "-")
+	if alias, ok := bondModeAliases[normalized]; ok {
+		normalized = alias
 	}
-	
-	// Set gateways
-	if formData.Gateway4 != "" {
-		bridgeConfig.Gateway4 = formData.Gateway4
+	if !slices.Contains(bondModes, normalized) {
+		return "", fmt.Errorf("unknown bonding mode %q (expected one of %s)", mode, strings.Join(bondModes, ", "))
+	}
+	return normalized, nil
+}
+
+// Unit suffixes accepted by parseIntValue, mapped to the multiplier that
+// converts them to the unit netplan expects
+var (
+	mtuUnits      = map[string]int{"": 1, "b": 1, "bytes": 1}
+	intervalUnits = map[string]int{"": 1, "ms": 1, "s": 1000}
+	countUnits    = map[string]int{"": 1}
+)
+
+// parseIntValue parses a non-negative number with an optional unit suffix,
+// e.g. "9000b" for an MTU or "1s" for an interval given in milliseconds.
+// Unknown suffixes are rejected rather than silently ignored.
+func parseIntValue(input string, units map[string]int) (int, error) {
+	input = strings.TrimSpace(input)
+	end := 0
+	for end < len(input) && input[end] >= '0' && input[end] <= '9' {
+		end++
 	}
-	if formData.Gateway6 != "" {
-		bridgeConfig.Gateway6 = formData.Gateway6
+	if end == 0 {
+		return 0, fmt.Errorf("%q is not a number", input)
 	}
 	
-	// Parse nameservers
-	if formData.Nameservers != "" {
-		nameservers := parseCommaSeparated(formData.Nameservers)
-		bridgeConfig.Nameservers = &NameserversConfig{Addresses: nameservers}
+	number, err := strconv.Atoi(input[:end])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", input)
 	}
 	
-	config.Network.Bridges[formData.InterfaceName] = bridgeConfig
-	return config, nil
+	suffix := strings.ToLower(strings.TrimSpace(input[end:]))
+	multiplier, ok := units[suffix]
+	if !ok {
+		var allowed []string
+		for _, unit := range sortedKeys(units) {
+			if unit != "" {
+				allowed = append(allowed, unit)
+			}
+		}
+		if len(allowed) == 0 {
+			return 0, fmt.Errorf("%q must be a plain number", input)
+		}
+		return 0, fmt.Errorf("%q has unknown unit %q (expected a plain number or one of: %s)", input, suffix, strings.Join(allowed, ", "))
+	}
+	
+	return number * multiplier, nil
 }
 
 func parseCommaSeparated(input string) []string {
@@ -720,108 +2618,705 @@ func parseKeyValuePairs(input string) map[string]interface{} {
 	return result
 }
 
+// parseRoutes parses one route per line in the form "to via [metric] [on-link]",
+// e.g. "10.0.0.0/8 192.168.1.1 100" or "default 10.255.0.1 on-link". A
+// route MTU may be added anywhere after the destination as "mtu 1400".
+func parseRoutes(input string) ([]Route, error) {
+	var routes []Route
+	
+	for i, line := range strings.Split(input, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		
+		route := Route{}
+		if j := slices.Index(fields, "mtu"); j > 0 {
+			if j+1 == len(fields) {
+				return nil, fmt.Errorf("line %d: mtu needs a value", i+1)
+			}
+			mtu, err := strconv.Atoi(fields[j+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid mtu %q", i+1, fields[j+1])
+			}
+			route.MTU = mtu
+			fields = slices.Delete(fields, j, j+2)
+		}
+		if len(fields) > 1 && fields[len(fields)-1] == "on-link" {
+			route.OnLink = true
+			fields = fields[:len(fields)-1]
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected \"to via [metric] [on-link]\", got %q", i+1, strings.TrimSpace(line))
+		}
+		
+		route.To = fields[0]
+		if len(fields) > 1 {
+			route.Via = fields[1]
+		}
+		if len(fields) > 2 {
+			metric, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid metric %q", i+1, fields[2])
+			}
+			route.Metric = metric
+		}
+		
+		if err := validateRoute(route); err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		routes = append(routes, route)
+	}
+	
+	return routes, nil
+}
+
+// parseRoutesCSV parses routes in CSV form; the first row is a header naming
+// the columns (to, via, metric, table, mtu, on-link) in any order
+func parseRoutesCSV(input string) ([]Route, error) {
+	reader := csv.NewReader(strings.NewReader(input))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "to", "via", "metric", "table", "mtu", "on-link":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q in header (expected to, via, metric, table, mtu, on-link)", name)
+		}
+	}
+	if _, ok := columns["to"]; !ok {
+		return nil, fmt.Errorf("header must include a \"to\" column")
+	}
+	
+	var routes []Route
+	for _, record := range records[1:] {
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		
+		route := Route{To: value("to"), Via: value("via")}
+		if route.To == "" && route.Via == "" && value("metric") == "" && value("table") == "" && value("mtu") == "" && value("on-link") == "" {
+			continue
+		}
+		if metric := value("metric"); metric != "" {
+			route.Metric, err = strconv.Atoi(metric)
+			if err != nil {
+				return nil, fmt.Errorf("route to %s: invalid metric %q", route.To, metric)
+			}
+		}
+		if table := value("table"); table != "" {
+			route.Table, err = strconv.Atoi(table)
+			if err != nil {
+				return nil, fmt.Errorf("route to %s: invalid table %q", route.To, table)
+			}
+		}
+		if mtu := value("mtu"); mtu != "" {
+			route.MTU, err = strconv.Atoi(mtu)
+			if err != nil {
+				return nil, fmt.Errorf("route to %s: invalid mtu %q", route.To, mtu)
+			}
+		}
+		if onLink := value("on-link"); onLink != "" {
+			route.OnLink, err = strconv.ParseBool(onLink)
+			if err != nil {
+				return nil, fmt.Errorf("route to %s: invalid on-link %q", route.To, onLink)
+			}
+		}
+		
+		if err := validateRoute(route); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	
+	return routes, nil
+}
+
+// dedupeRoutes drops exact duplicate routes, keeping the first, and rejects
+// routes to the same destination, metric and table that differ otherwise,
+// since the kernel can only install one of them. Default routes are told
+// apart by the address family of their gateway, as "default" stands for
+// both 0.0.0.0/0 and ::/0.
+func dedupeRoutes(routes []Route) ([]Route, error) {
+	type routeKey struct {
+		to     string
+		ipv6   bool
+		metric int
+		table  int
+	}
+	seen := make(map[routeKey]Route)
+	
+	var result []Route
+	for _, route := range routes {
+		key := routeKey{route.To, route.To == "default" && strings.Contains(route.Via, ":"), route.Metric, route.Table}
+		if existing, ok := seen[key]; ok {
+			if existing == route {
+				continue
+			}
+			if existing.Via != route.Via {
+				return nil, fmt.Errorf("conflicting routes to %s with metric %d: via %q and via %q", route.To, route.Metric, existing.Via, route.Via)
+			}
+			return nil, fmt.Errorf("conflicting routes to %s with metric %d via %q: they differ in mtu or on-link", route.To, route.Metric, route.Via)
+		}
+		seen[key] = route
+		result = append(result, route)
+	}
+	
+	return result, nil
+}
+
+func validateRoute(route Route) error {
+	if route.To == "" {
+		return fmt.Errorf("route destination is required")
+	}
+	if route.To != "default" {
+		if _, _, err := net.ParseCIDR(route.To); err != nil && net.ParseIP(route.To) == nil {
+			return fmt.Errorf("invalid route destination %q", route.To)
+		}
+	}
+	if route.Via != "" && net.ParseIP(route.Via) == nil {
+		return fmt.Errorf("invalid route gateway %q for %s", route.Via, route.To)
+	}
+	if route.Metric < 0 {
+		return fmt.Errorf("invalid route metric %d for %s", route.Metric, route.To)
+	}
+	if route.Table < 0 {
+		return fmt.Errorf("invalid route table %d for %s", route.Table, route.To)
+	}
+	if route.MTU != 0 && (route.MTU < minMTU || route.MTU > maxMTU) {
+		return fmt.Errorf("invalid route mtu %d for %s (expected %d-%d)", route.MTU, route.To, minMTU, maxMTU)
+	}
+	if route.OnLink && route.Via == "" {
+		return fmt.Errorf("on-link route to %s requires a gateway (via)", route.To)
+	}
+	return nil
+}
+
+// macAddressKeywords are the special values netplan accepts for macaddress
+// in place of a literal MAC
+var macAddressKeywords = map[string]bool{
+	"random":   true,
+	"stable":   true,
+	"preserve": true,
+}
+
+var macAddressPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
+
+func validateMACAddress(mac string) error {
+	if macAddressKeywords[mac] {
+		return nil
+	}
+	if !macAddressPattern.MatchString(mac) {
+		return fmt.Errorf("%q is not a MAC address (expected xx:xx:xx:xx:xx:xx, random, stable or preserve)", mac)
+	}
+	return nil
+}
+
+// formatMACAddress quotes literal MACs so YAML 1.1 parsers don't read an
+// all-digit MAC as a sexagesimal number; keywords are emitted as-is
+func formatMACAddress(mac string) string {
+	if macAddressKeywords[mac] {
+		return mac
+	}
+	return fmt.Sprintf("%q", mac)
+}
+
+// configToYAML renders the config as netplan YAML
 func configToYAML(config *NetplanConfig) string {
 	var sb strings.Builder
+	writeConfigYAML(&sb, config) // writes to a strings.Builder can't fail
+	return sb.String()
+}
+
+// renderYAML returns the YAML sent back for a form: the config, written
+// with the output template if there is one, followed by the embedded
+// source if requested. It has no byte order mark and ends with exactly one
+// newline, unless the form asks for none.
+func renderYAML(config *NetplanConfig, formData FormData) string {
+	yaml := outputYAML(config)
+	if formData.EmbedSource {
+		yaml = appendSourceComment(yaml, formData)
+	}
+	yaml = strings.TrimRight(yaml, "\n")
+	if !formData.OmitTrailingNewline {
+		yaml += "\n"
+	}
+	return yaml
+}
+
+// sourceCommentPrefix starts the comment line that holds the embedded
+// FormData
+const sourceCommentPrefix = "# netplan-web-generator source: "
+
+// appendSourceComment appends the form data as compact JSON on a comment
+// line; JSON escapes newlines inside strings, so it always fits on one line
+func appendSourceComment(yaml string, formData FormData) string {
+	source, err := json.Marshal(formData)
+	if err != nil {
+		return yaml
+	}
+	return yaml + "\n" + sourceCommentPrefix + string(source) + "\n"
+}
+
+// extractSource returns the form data embedded by appendSourceComment, or
+// nil if the YAML has none
+func extractSource(yaml string) (*FormData, error) {
+	for _, line := range strings.Split(yaml, "\n") {
+		source, ok := strings.CutPrefix(line, sourceCommentPrefix)
+		if !ok {
+			continue
+		}
+		var formData FormData
+		if err := json.Unmarshal([]byte(source), &formData); err != nil {
+			return nil, fmt.Errorf("invalid embedded source: %v", err)
+		}
+		return &formData, nil
+	}
+	return nil, nil
+}
+
+// yamlWriter wraps an io.Writer, remembering the first write error so the
+// YAML can be written without checking every call
+type yamlWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (yw *yamlWriter) WriteString(s string) {
+	if yw.err == nil {
+		_, yw.err = io.WriteString(yw.w, s)
+	}
+}
+
+// writeConfigYAML streams the config as netplan YAML to w and returns the
+// first write error, if any
+func writeConfigYAML(w io.Writer, config *NetplanConfig) error {
+	if config.IndentWidth == 4 {
+		var sb strings.Builder
+		plain := *config
+		plain.IndentWidth = 0
+		writeConfigYAML(&sb, &plain)
+		_, err := io.WriteString(w, reindentYAML(sb.String()))
+		return err
+	}
+	
+	yw := &yamlWriter{w: w}
 	
-	sb.WriteString("network:\n")
-	sb.WriteString(fmt.Sprintf("  version: %d\n", config.Network.Version))
-	sb.WriteString(fmt.Sprintf("  renderer: %s\n", config.Network.Renderer))
+	yw.WriteString("network:\n")
+	yw.WriteString(fmt.Sprintf("  version: %d\n", config.Network.Version))
+	if config.Network.Renderer != "" {
+		yw.WriteString(fmt.Sprintf("  renderer: %s\n", config.Network.Renderer))
+	}
 	
 	// Ethernet interfaces
 	if len(config.Network.Ethernets) > 0 {
-		sb.WriteString("  ethernets:\n")
-		for name, eth := range config.Network.Ethernets {
-			sb.WriteString(fmt.Sprintf("    %s:\n", name))
-			writeInterfaceConfig(&sb, eth.DHCP4, eth.DHCP6, eth.Addresses, eth.Gateway4, eth.Gateway6, eth.Nameservers, eth.DHCP4Overrides, eth.DHCP6Overrides)
+		yw.WriteString("  ethernets:\n")
+		writeSectionRenderer(yw, config.Network, "ethernets")
+		for _, name := range sortedKeys(config.Network.Ethernets) {
+			eth := config.Network.Ethernets[name]
+			settings := config.outputSettings(eth.settings())
+			empty := reflect.ValueOf(settings).IsZero() && eth.Match == nil && eth.SetName == "" && eth.NetworkManager == nil
+			writeInterfaceKey(yw, config, name, empty)
+			if eth.Match != nil {
+				yw.WriteString("      match:\n")
+				yw.WriteString(fmt.Sprintf("        macaddress: %s\n", formatMACAddress(eth.Match.MACAddress)))
+			}
+			if eth.SetName != "" {
+				yw.WriteString(fmt.Sprintf("      set-name: %s\n", eth.SetName))
+			}
+			writeInterfaceConfig(yw, settings)
+			if eth.NetworkManager != nil && len(eth.NetworkManager.Passthrough) > 0 {
+				yw.WriteString("      networkmanager:\n")
+				yw.WriteString("        passthrough:\n")
+				for _, key := range sortedKeys(eth.NetworkManager.Passthrough) {
+					yw.WriteString(fmt.Sprintf("          %s: %s\n", key, formatYAMLScalar(eth.NetworkManager.Passthrough[key])))
+				}
+			}
 		}
 	}
 	
 	// Bond interfaces
 	if len(config.Network.Bonds) > 0 {
-		sb.WriteString("  bonds:\n")
-		for name, bond := range config.Network.Bonds {
-			sb.WriteString(fmt.Sprintf("    %s:\n", name))
-			sb.WriteString("      interfaces:\n")
+		yw.WriteString("  bonds:\n")
+		writeSectionRenderer(yw, config.Network, "bonds")
+		for _, name := range sortedKeys(config.Network.Bonds) {
+			bond := config.Network.Bonds[name]
+			writeInterfaceKey(yw, config, name, false)
+			yw.WriteString("      interfaces:\n")
 			for _, iface := range bond.Interfaces {
-				sb.WriteString(fmt.Sprintf("        - %s\n", iface))
+				yw.WriteString(fmt.Sprintf("        - %s\n", iface))
+			}
+			yw.WriteString("      parameters:\n")
+			yw.WriteString(fmt.Sprintf("        mode: %s\n", bond.Parameters.Mode))
+			if bond.Parameters.MIIMonitorInterval != 0 {
+				yw.WriteString(fmt.Sprintf("        mii-monitor-interval: %d\n", bond.Parameters.MIIMonitorInterval))
+			}
+			if bond.Parameters.UpDelay != 0 {
+				yw.WriteString(fmt.Sprintf("        up-delay: %d\n", bond.Parameters.UpDelay))
+			}
+			if bond.Parameters.DownDelay != 0 {
+				yw.WriteString(fmt.Sprintf("        down-delay: %d\n", bond.Parameters.DownDelay))
+			}
+			if bond.Parameters.MinLinks != 0 {
+				yw.WriteString(fmt.Sprintf("        min-links: %d\n", bond.Parameters.MinLinks))
 			}
-			sb.WriteString("      parameters:\n")
-			sb.WriteString(fmt.Sprintf("        mode: %s\n", bond.Parameters.Mode))
-			writeInterfaceConfig(&sb, bond.DHCP4, bond.DHCP6, bond.Addresses, bond.Gateway4, bond.Gateway6, bond.Nameservers, nil, nil)
+			if bond.Parameters.AllMembersActive != nil {
+				yw.WriteString(fmt.Sprintf("        all-members-active: %t\n", *bond.Parameters.AllMembersActive))
+			}
+			writeInterfaceConfig(yw, config.outputSettings(bond.settings()))
 		}
 	}
 	
 	// Bridge interfaces
 	if len(config.Network.Bridges) > 0 {
-		sb.WriteString("  bridges:\n")
-		for name, bridge := range config.Network.Bridges {
-			sb.WriteString(fmt.Sprintf("    %s:\n", name))
-			sb.WriteString("      interfaces:\n")
-			for _, iface := range bridge.Interfaces {
-				sb.WriteString(fmt.Sprintf("        - %s\n", iface))
+		yw.WriteString("  bridges:\n")
+		writeSectionRenderer(yw, config.Network, "bridges")
+		for _, name := range sortedKeys(config.Network.Bridges) {
+			bridge := config.Network.Bridges[name]
+			writeInterfaceKey(yw, config, name, false)
+			if len(bridge.Interfaces) > 0 {
+				yw.WriteString("      interfaces:\n")
+				for _, iface := range bridge.Interfaces {
+					yw.WriteString(fmt.Sprintf("        - %s\n", iface))
+				}
 			}
-			writeInterfaceConfig(&sb, bridge.DHCP4, bridge.DHCP6, bridge.Addresses, bridge.Gateway4, bridge.Gateway6, bridge.Nameservers, nil, nil)
+			writeInterfaceConfig(yw, config.outputSettings(bridge.settings()))
 		}
 	}
 	
-	return sb.String()
+	// VLANs
+	if len(config.Network.VLANs) > 0 {
+		yw.WriteString("  vlans:\n")
+		writeSectionRenderer(yw, config.Network, "vlans")
+		for _, name := range sortedKeys(config.Network.VLANs) {
+			vlan := config.Network.VLANs[name]
+			writeInterfaceKey(yw, config, name, false)
+			yw.WriteString(fmt.Sprintf("      id: %d\n", vlan.ID))
+			yw.WriteString(fmt.Sprintf("      link: %s\n", vlan.Link))
+			writeInterfaceConfig(yw, config.outputSettings(vlan.settings()))
+		}
+	}
+	
+	// Dummy devices
+	if len(config.Network.DummyDevices) > 0 {
+		yw.WriteString("  dummy-devices:\n")
+		writeSectionRenderer(yw, config.Network, "dummy-devices")
+		for _, name := range sortedKeys(config.Network.DummyDevices) {
+			writeInterfaceKey(yw, config, name, false)
+			writeInterfaceConfig(yw, config.outputSettings(config.Network.DummyDevices[name].settings()))
+		}
+	}
+	
+	// Modems
+	if len(config.Network.Modems) > 0 {
+		yw.WriteString("  modems:\n")
+		writeSectionRenderer(yw, config.Network, "modems")
+		for _, name := range sortedKeys(config.Network.Modems) {
+			modem := config.Network.Modems[name]
+			writeInterfaceKey(yw, config, name, false)
+			if modem.APN != "" {
+				yw.WriteString(fmt.Sprintf("      apn: %s\n", formatYAMLScalar(modem.APN)))
+			}
+			if modem.AutoConfig {
+				yw.WriteString("      auto-config: true\n")
+			}
+			if modem.Number != "" {
+				yw.WriteString(fmt.Sprintf("      number: %s\n", formatYAMLScalar(modem.Number)))
+			}
+			if modem.PIN != "" {
+				yw.WriteString(fmt.Sprintf("      pin: %s\n", formatYAMLScalar(modem.PIN)))
+			}
+			writeInterfaceConfig(yw, config.outputSettings(modem.settings()))
+		}
+	}
+	
+	// NetworkManager passthrough devices
+	if len(config.Network.NMDevices) > 0 {
+		yw.WriteString("  nm-devices:\n")
+		writeSectionRenderer(yw, config.Network, "nm-devices")
+		for _, name := range sortedKeys(config.Network.NMDevices) {
+			passthrough := config.Network.NMDevices[name].NetworkManager.Passthrough
+			writeInterfaceKey(yw, config, name, false)
+			yw.WriteString("      networkmanager:\n")
+			yw.WriteString("        passthrough:\n")
+			for _, key := range sortedKeys(passthrough) {
+				yw.WriteString(fmt.Sprintf("          %s: %s\n", key, formatYAMLScalar(passthrough[key])))
+			}
+		}
+	}
+	
+	if config.Disabled != nil {
+		writeDisabledComment(yw, config.Disabled)
+	}
+	
+	return yw.err
+}
+
+// writeDisabledComment writes the sections of the disabled interfaces'
+// config commented out, so that removing the "# " prefixes puts them back
+// under network:
+func writeDisabledComment(yw *yamlWriter, disabled *NetplanConfig) {
+	lines := strings.Split(strings.TrimSuffix(configToYAML(disabled), "\n"), "\n")
+	yw.WriteString("# Disabled interfaces:\n")
+	// The first lines are network:, version: and renderer:, if any
+	skip := 2
+	if disabled.Network.Renderer != "" {
+		skip = 3
+	}
+	for _, line := range lines[skip:] {
+		yw.WriteString("# " + line + "\n")
+	}
+}
+
+// reindentYAML converts YAML written with 2-space indentation to 4 spaces
+// per level. A list item whose mapping continues on the following lines is
+// padded after its dash ("-   to: ...") so its keys stay aligned with the
+// doubled indentation of those lines.
+func reindentYAML(yaml string) string {
+	lines := strings.Split(yaml, "\n")
+	indents := make([]int, len(lines))
+	for i, line := range lines {
+		indents[i] = len(line) - len(strings.TrimLeft(line, " "))
+	}
+	
+	for i, line := range lines {
+		content := line[indents[i]:]
+		if strings.HasPrefix(content, "- ") && i+1 < len(lines) && indents[i+1] == indents[i]+2 {
+			content = "-   " + content[2:]
+		}
+		lines[i] = strings.Repeat(" ", 2*indents[i]) + content
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeInterfaceKey starts an interface's mapping, preceded by its
+// description as a comment; an empty mapping is written as {}
+func writeInterfaceKey(yw *yamlWriter, config *NetplanConfig, name string, empty bool) {
+	if description := config.Descriptions[name]; description != "" {
+		yw.WriteString(fmt.Sprintf("    # %s\n", description))
+	}
+	if empty {
+		yw.WriteString(fmt.Sprintf("    %s: {}\n", name))
+		return
+	}
+	yw.WriteString(fmt.Sprintf("    %s:\n", name))
+}
+
+// outputSettings returns the settings as they are written: promiscuous
+// mode, which has no netplan key, is left out; with Explicit, unset dhcp4
+// and dhcp6 are written as false; and with MinimalOutput, dhcp4 and dhcp6
+// are left out when false, as that is netplan's default. dhcp4: true is
+// always kept, since leaving it out would turn DHCP off.
+func (c *NetplanConfig) outputSettings(s interfaceSettings) interfaceSettings {
+	s.Promiscuous = false
+	if c.Explicit {
+		disabled := false
+		if s.DHCP4 == nil {
+			s.DHCP4 = &disabled
+		}
+		if s.DHCP6 == nil {
+			s.DHCP6 = &disabled
+		}
+		return s
+	}
+	if !c.MinimalOutput {
+		return s
+	}
+	if s.DHCP4 != nil && !*s.DHCP4 {
+		s.DHCP4 = nil
+	}
+	if s.DHCP6 != nil && !*s.DHCP6 {
+		s.DHCP6 = nil
+	}
+	return s
+}
+
+// maxDescriptionLength is the longest interface description kept, in runes
+const maxDescriptionLength = 200
+
+// sanitizeDescription makes a description safe to write as a single YAML
+// comment line: control characters such as newlines become spaces, runs of
+// whitespace are collapsed and overly long descriptions are truncated
+func sanitizeDescription(description string) string {
+	description = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '\uFEFF' {
+			return ' '
+		}
+		return r
+	}, description)
+	description = strings.Join(strings.Fields(description), " ")
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		description = strings.TrimSpace(string(runes[:maxDescriptionLength]))
+	}
+	return description
+}
+
+// writeSectionRenderer writes the renderer key of a section that
+// overrides the network-wide renderer
+func writeSectionRenderer(yw *yamlWriter, network NetworkConfig, section string) {
+	if renderer, ok := network.SectionRenderers[section]; ok {
+		yw.WriteString(fmt.Sprintf("    renderer: %s\n", renderer))
+	}
 }
 
-func writeInterfaceConfig(sb *strings.Builder, dhcp4, dhcp6 *bool, addresses []string, gateway4, gateway6 string, nameservers *NameserversConfig, dhcp4Overrides, dhcp6Overrides map[string]interface{}) {
-	if dhcp4 != nil && *dhcp4 {
-		sb.WriteString("      dhcp4: true\n")
+func writeInterfaceConfig(yw *yamlWriter, s interfaceSettings) {
+	if s.MACAddress != "" {
+		yw.WriteString(fmt.Sprintf("      macaddress: %s\n", formatMACAddress(s.MACAddress)))
 	}
-	if dhcp6 != nil && *dhcp6 {
-		sb.WriteString("      dhcp6: true\n")
+	if s.Optional {
+		yw.WriteString("      optional: true\n")
+	}
+	if s.Critical {
+		yw.WriteString("      critical: true\n")
+	}
+	if s.MTU != 0 {
+		yw.WriteString(fmt.Sprintf("      mtu: %d\n", s.MTU))
+	}
+	
+	if s.DHCP4 != nil {
+		yw.WriteString(fmt.Sprintf("      dhcp4: %t\n", *s.DHCP4))
+	}
+	if s.DHCP6 != nil {
+		yw.WriteString(fmt.Sprintf("      dhcp6: %t\n", *s.DHCP6))
+	}
+	if s.AcceptRA != nil {
+		yw.WriteString(fmt.Sprintf("      accept-ra: %t\n", *s.AcceptRA))
+	}
+	if len(s.LinkLocal) > 0 {
+		yw.WriteString(fmt.Sprintf("      link-local: [%s]\n", strings.Join(s.LinkLocal, ", ")))
 	}
 	
-	if len(addresses) > 0 {
-		sb.WriteString("      addresses:\n")
-		for _, addr := range addresses {
-			sb.WriteString(fmt.Sprintf("        - %s\n", addr))
+	if len(s.Addresses) > 0 {
+		yw.WriteString("      addresses:\n")
+		for _, addr := range s.Addresses {
+			yw.WriteString(fmt.Sprintf("        - %s\n", addr))
 		}
 	}
 	
-	if gateway4 != "" {
-		sb.WriteString(fmt.Sprintf("      gateway4: %s\n", gateway4))
+	if s.Gateway4 != "" {
+		yw.WriteString(fmt.Sprintf("      gateway4: %s\n", s.Gateway4))
+	}
+	if s.Gateway6 != "" {
+		yw.WriteString(fmt.Sprintf("      gateway6: %s\n", s.Gateway6))
 	}
-	if gateway6 != "" {
-		sb.WriteString(fmt.Sprintf("      gateway6: %s\n", gateway6))
+	
+	if len(s.Routes) > 0 {
+		yw.WriteString("      routes:\n")
+		for _, route := range s.Routes {
+			yw.WriteString(fmt.Sprintf("        - to: %s\n", route.To))
+			if route.Via != "" {
+				yw.WriteString(fmt.Sprintf("          via: %s\n", route.Via))
+			}
+			if route.Metric != 0 {
+				yw.WriteString(fmt.Sprintf("          metric: %d\n", route.Metric))
+			}
+			if route.Table != 0 {
+				yw.WriteString(fmt.Sprintf("          table: %d\n", route.Table))
+			}
+			if route.MTU != 0 {
+				yw.WriteString(fmt.Sprintf("          mtu: %d\n", route.MTU))
+			}
+			if route.OnLink {
+				yw.WriteString("          on-link: true\n")
+			}
+		}
 	}
 	
-	if nameservers != nil && len(nameservers.Addresses) > 0 {
-		sb.WriteString("      nameservers:\n")
-		sb.WriteString("        addresses:\n")
-		for _, ns := range nameservers.Addresses {
-			sb.WriteString(fmt.Sprintf("          - %s\n", ns))
+	if s.Nameservers != nil && (s.Nameservers.Addresses != nil || len(s.Nameservers.Search) > 0) {
+		yw.WriteString("      nameservers:\n")
+		switch {
+		case len(s.Nameservers.Addresses) > 0:
+			yw.WriteString("        addresses:\n")
+			for _, ns := range s.Nameservers.Addresses {
+				yw.WriteString(fmt.Sprintf("          - %s\n", ns))
+			}
+		case s.Nameservers.Addresses != nil:
+			yw.WriteString("        addresses: []\n")
+		}
+		if len(s.Nameservers.Search) > 0 {
+			yw.WriteString("        search:\n")
+			for _, domain := range s.Nameservers.Search {
+				yw.WriteString(fmt.Sprintf("          - %s\n", domain))
+			}
 		}
 	}
 	
-	if len(dhcp4Overrides) > 0 {
-		sb.WriteString("      dhcp4-overrides:\n")
-		for key, value := range dhcp4Overrides {
-			sb.WriteString(fmt.Sprintf("        %s: %v\n", key, formatYAMLValue(value)))
+	if len(s.DHCP4Overrides) > 0 {
+		yw.WriteString("      dhcp4-overrides:\n")
+		for _, key := range sortedKeys(s.DHCP4Overrides) {
+			value := s.DHCP4Overrides[key]
+			yw.WriteString(fmt.Sprintf("        %s: %v\n", key, formatYAMLScalar(value)))
 		}
 	}
 	
-	if len(dhcp6Overrides) > 0 {
-		sb.WriteString("      dhcp6-overrides:\n")
-		for key, value := range dhcp6Overrides {
-			sb.WriteString(fmt.Sprintf("        %s: %v\n", key, formatYAMLValue(value)))
+	if len(s.DHCP6Overrides) > 0 {
+		yw.WriteString("      dhcp6-overrides:\n")
+		for _, key := range sortedKeys(s.DHCP6Overrides) {
+			value := s.DHCP6Overrides[key]
+			yw.WriteString(fmt.Sprintf("        %s: %v\n", key, formatYAMLScalar(value)))
 		}
 	}
 }
 
-func formatYAMLValue(value interface{}) string {
-	switch v := value.(type) {
-	case bool:
-		if v {
-			return "true"
+// sortedKeys returns the keys of a map in sorted order, so that output
+// generated from maps is stable between runs. Lists such as addresses,
+// routes and nameservers are never sorted; they keep the user's input order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// highlightYAML wraps the YAML in <pre><code> with every key, value and
+// comment in its own span; all text is HTML-escaped
+func highlightYAML(yaml string) string {
+	var sb strings.Builder
+	
+	sb.WriteString("<pre><code class=\"language-yaml\">")
+	for _, line := range strings.Split(strings.TrimSuffix(yaml, "\n"), "\n") {
+		content := strings.TrimLeft(line, " ")
+		sb.WriteString(line[:len(line)-len(content)])
+		
+		if strings.HasPrefix(content, "#") {
+			sb.WriteString("<span class=\"yaml-comment\">" + html.EscapeString(content) + "</span>\n")
+			continue
 		}
-		return "false"
-	case string:
-		return v
-	default:
-		return fmt.Sprintf("%v", v)
+		
+		if strings.HasPrefix(content, "- ") {
+			sb.WriteString("- ")
+			content = content[2:]
+		}
+		
+		if key, value, found := strings.Cut(content, ": "); found {
+			sb.WriteString("<span class=\"yaml-key\">" + html.EscapeString(key) + "</span>: ")
+			sb.WriteString("<span class=\"yaml-value\">" + html.EscapeString(value) + "</span>")
+		} else if strings.HasSuffix(content, ":") {
+			sb.WriteString("<span class=\"yaml-key\">" + html.EscapeString(strings.TrimSuffix(content, ":")) + "</span>:")
+		} else {
+			sb.WriteString("<span class=\"yaml-value\">" + html.EscapeString(content) + "</span>")
+		}
+		sb.WriteString("\n")
 	}
-}
\ No newline at end of file
+	sb.WriteString("</code></pre>")
+	
+	return sb.String()
+}
@@ -12,6 +12,16 @@ the Free Software Foundation, either version 3 of the License, or
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -73,6 +83,21 @@ func TestParseKeyValuePairs(t *testing.T) {
 	}
 }
 
+func TestNormalizeOverrideKeys(t *testing.T) {
+	overrides, err := normalizeOverrideKeys(parseKeyValuePairs("use_dns=false, routeMetric=200, Send-Hostname=true, custom_key=1"))
+	if err != nil {
+		t.Fatalf("normalizeOverrideKeys failed: %v", err)
+	}
+	expected := map[string]interface{}{"use-dns": false, "route-metric": 200, "send-hostname": true, "custom_key": 1}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected %v, got %v", expected, overrides)
+	}
+	
+	if _, err := normalizeOverrideKeys(parseKeyValuePairs("use_dns=false, use-dns=true")); err == nil || !strings.Contains(err.Error(), "use-dns is set to both") {
+		t.Errorf("Expected a conflicting use-dns error, got %v", err)
+	}
+}
+
 func TestGenerateEthernetConfig(t *testing.T) {
 	config := &NetplanConfig{
 		Network: NetworkConfig{
@@ -82,9 +107,11 @@ func TestGenerateEthernetConfig(t *testing.T) {
 	}
 
 	formData := FormData{
-		InterfaceName: "eth0",
-		UseStatic:     false,
-		Renderer:      "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:      "eth0",
+			UseStatic: false,
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateEthernetConfig(config, formData)
@@ -115,12 +142,14 @@ func TestGenerateStaticEthernetConfig(t *testing.T) {
 	}
 
 	formData := FormData{
-		InterfaceName: "eth0",
-		UseStatic:     true,
-		Addresses:     "192.168.1.100/24",
-		Gateway4:      "192.168.1.1",
-		Nameservers:   "8.8.8.8,8.8.4.4",
-		Renderer:      "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:        "eth0",
+			UseStatic:   true,
+			Addresses:   "192.168.1.100/24",
+			Gateway4:    "192.168.1.1",
+			Nameservers: "8.8.8.8,8.8.4.4",
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateEthernetConfig(config, formData)
@@ -183,11 +212,13 @@ func TestBondWithEthernetDeclarations(t *testing.T) {
 	}
 
 	formData := FormData{
-		InterfaceName:  "bond0",
-		BondInterfaces: "eth0,eth1",
-		BondMode:       "active-backup",
-		UseStatic:      false,
-		Renderer:       "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:           "bond0",
+			BondInterfaces: "eth0,eth1",
+			BondMode:       "active-backup",
+			UseStatic:      false,
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateBondConfig(config, formData)
@@ -229,9 +260,11 @@ func TestStaticWithoutAddresses(t *testing.T) {
 
 	// Test ethernet static without addresses
 	formData := FormData{
-		InterfaceName: "eth0",
-		UseStatic:     true,
-		Renderer:      "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:      "eth0",
+			UseStatic: true,
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateEthernetConfig(config, formData)
@@ -247,6 +280,28 @@ func TestStaticWithoutAddresses(t *testing.T) {
 	}
 }
 
+func TestNoAddressingWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2", BondMode: "active-backup"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	// The bond members are meant to have no addressing of their own
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: has no static addresses and neither DHCP nor router advertisements enabled") {
+		t.Errorf("Expected one no addressing warning for eth0, got %v", config.Warnings)
+	}
+	if yaml := configToYAML(config); !strings.Contains(yaml, "    eth0:\n      dhcp4: false\n") {
+		t.Errorf("Expected eth0 to still be written with dhcp4: false, got:\n%s", yaml)
+	}
+}
+
 func TestGenerateBondConfig(t *testing.T) {
 	config := &NetplanConfig{
 		Network: NetworkConfig{
@@ -256,11 +311,13 @@ func TestGenerateBondConfig(t *testing.T) {
 	}
 
 	formData := FormData{
-		InterfaceName:  "bond0",
-		BondInterfaces: "eth0,eth1",
-		BondMode:       "active-backup",
-		UseStatic:      false,
-		Renderer:       "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:           "bond0",
+			BondInterfaces: "eth0,eth1",
+			BondMode:       "active-backup",
+			UseStatic:      false,
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateBondConfig(config, formData)
@@ -310,10 +367,12 @@ func TestGenerateBridgeConfig(t *testing.T) {
 	}
 
 	formData := FormData{
-		InterfaceName:    "br0",
-		BridgeInterfaces: "eth0,eth1",
-		UseStatic:        false,
-		Renderer:         "networkd",
+		Interfaces: []InterfaceDefinition{{
+			Name:             "br0",
+			BridgeInterfaces: "eth0,eth1",
+			UseStatic:        false,
+		}},
+		Renderer: "networkd",
 	}
 
 	result, err := generateBridgeConfig(config, formData)
@@ -348,4 +407,2468 @@ func TestGenerateBridgeConfig(t *testing.T) {
 			t.Errorf("Expected ethernet interface %s to have dhcp4: false", ifaceName)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestMACAddressKeyword(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:       "ethernet",
+			Name:       "eth0",
+			MACAddress: "random",
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, "macaddress: random\n") {
+		t.Errorf("Expected unquoted 'macaddress: random', got:\n%s", yaml)
+	}
+}
+
+func TestMACAddressLiteral(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:       "ethernet",
+			Name:       "eth0",
+			MACAddress: "52:54:00:12:34:56",
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, `macaddress: "52:54:00:12:34:56"`) {
+		t.Errorf("Expected literal MAC address, got:\n%s", yaml)
+	}
+
+	formData.Interfaces[0].MACAddress = "52:54:00:12:34"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected error for malformed MAC address")
+	}
+}
+
+func TestIPv4OnlyEthernet(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:     "ethernet",
+			Name:     "eth0",
+			IPv4Only: true,
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+
+	expectedStrings := []string{
+		"dhcp4: true",
+		"dhcp6: false",
+		"accept-ra: false",
+		"link-local: [ipv4]",
+	}
+
+	for _, expected := range expectedStrings {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+		}
+	}
+}
+
+func TestIPv4OnlyKeepsExplicitSettings(t *testing.T) {
+	acceptRA := true
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			IPv4Only:  true,
+			AcceptRA:  &acceptRA,
+			LinkLocal: "ipv4,ipv6",
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, "accept-ra: true") || !strings.Contains(yaml, "link-local: [ipv4, ipv6]") {
+		t.Errorf("Expected explicit accept-ra and link-local to be kept, got:\n%s", yaml)
+	}
+}
+
+func TestPreviewEscapesDescription(t *testing.T) {
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0","description":"<script>alert(1)</script>"}],"renderer":"networkd"}`
+	req := httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handlePreview(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	fragment := rec.Body.String()
+	if strings.Contains(fragment, "<script>") {
+		t.Errorf("Expected description to be escaped, got:\n%s", fragment)
+	}
+	if !strings.Contains(fragment, "&lt;script&gt;") {
+		t.Errorf("Expected escaped description in fragment, got:\n%s", fragment)
+	}
+	if !strings.HasPrefix(fragment, "<pre><code") || !strings.Contains(fragment, `<span class="yaml-key">renderer</span>`) {
+		t.Errorf("Expected highlighted <pre><code> fragment, got:\n%s", fragment)
+	}
+}
+
+func TestRenderPageEscapesErrorMessage(t *testing.T) {
+	form := url.Values{}
+	form.Set("interface_type", "bond")
+	form.Set("interface_name", "<script>alert(1)</script>")
+	form.Set("renderer", "networkd")
+
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	handleGenerate(rec, req)
+
+	page := rec.Body.String()
+	if strings.Contains(page, "<script>alert(1)</script>") {
+		t.Errorf("Expected interface name in error message to be escaped")
+	}
+	if !strings.Contains(page, "invalid interface name &#34;&lt;script&gt;alert(1)&lt;/script&gt;&#34;") {
+		t.Errorf("Expected escaped error message in rendered page")
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes("default 192.168.1.1\n\n10.0.0.0/8 192.168.1.254 100\n")
+	if err != nil {
+		t.Fatalf("parseRoutes failed: %v", err)
+	}
+
+	expected := []Route{
+		{To: "default", Via: "192.168.1.1"},
+		{To: "10.0.0.0/8", Via: "192.168.1.254", Metric: 100},
+	}
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes, got %v", len(expected), routes)
+	}
+	for i, route := range routes {
+		if route != expected[i] {
+			t.Errorf("Route %d = %+v, want %+v", i, route, expected[i])
+		}
+	}
+
+	if _, err := parseRoutes("10.0.0.0/8 not-an-ip"); err == nil {
+		t.Errorf("Expected error for invalid route gateway")
+	}
+}
+
+func TestParseRoutesCSV(t *testing.T) {
+	input := `to,via,metric,table
+10.0.0.0/8,192.168.1.1,100,
+"172.16.0.0/12", 192.168.1.2 ,200,10
+
+default,192.168.1.254,,
+`
+
+	routes, err := parseRoutesCSV(input)
+	if err != nil {
+		t.Fatalf("parseRoutesCSV failed: %v", err)
+	}
+
+	expected := []Route{
+		{To: "10.0.0.0/8", Via: "192.168.1.1", Metric: 100},
+		{To: "172.16.0.0/12", Via: "192.168.1.2", Metric: 200, Table: 10},
+		{To: "default", Via: "192.168.1.254"},
+	}
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes, got %v", len(expected), routes)
+	}
+	for i, route := range routes {
+		if route != expected[i] {
+			t.Errorf("Route %d = %+v, want %+v", i, route, expected[i])
+		}
+	}
+
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			RoutesCSV: input,
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	for _, expected := range []string{"routes:", "- to: 172.16.0.0/12", "via: 192.168.1.2", "metric: 200", "table: 10"} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+		}
+	}
+}
+
+func TestRouteMTU(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			Routes:    "10.8.0.0/16 192.168.1.254 mtu 1400 on-link",
+			RoutesCSV: "to,via,mtu\n172.16.0.0/12,192.168.1.253,1400\n",
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	for _, want := range []string{
+		"        - to: 10.8.0.0/16\n          via: 192.168.1.254\n          mtu: 1400\n          on-link: true\n",
+		"        - to: 172.16.0.0/12\n          via: 192.168.1.253\n          mtu: 1400\n",
+	} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected %q in:\n%s", want, yaml)
+		}
+	}
+	
+	for _, routes := range []string{"10.8.0.0/16 192.168.1.254 mtu 40", "10.8.0.0/16 192.168.1.254 mtu 70000", "10.8.0.0/16 192.168.1.254 mtu"} {
+		if _, err := parseRoutes(routes); err == nil {
+			t.Errorf("Expected an error for %q", routes)
+		}
+	}
+}
+
+func TestListOrderingIsStable(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:        "ethernet",
+				Name:        "eth1",
+				UseStatic:   true,
+				Addresses:   "192.168.1.30/24, 10.0.0.5/8, 192.168.1.2/24",
+				Routes:      "172.16.0.0/12 192.168.1.1\n10.1.0.0/16 10.0.0.1",
+				Nameservers: "9.9.9.9, 1.1.1.1, 8.8.8.8",
+			},
+			{
+				Type: "ethernet",
+				Name: "eth0",
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	expected := []string{
+		"- 192.168.1.30/24",
+		"- 10.0.0.5/8",
+		"- 192.168.1.2/24",
+		"- to: 172.16.0.0/12",
+		"- to: 10.1.0.0/16",
+		"- 9.9.9.9",
+		"- 1.1.1.1",
+		"- 8.8.8.8",
+	}
+
+	var first string
+	for run := 0; run < 20; run++ {
+		config, err := generateNetplanConfig(formData)
+		if err != nil {
+			t.Fatalf("generateNetplanConfig failed: %v", err)
+		}
+		yaml := configToYAML(config)
+
+		if run == 0 {
+			first = yaml
+			last := -1
+			for _, item := range expected {
+				index := strings.Index(yaml, item)
+				if index <= last {
+					t.Fatalf("Expected %q to follow the previous list item in input order, got:\n%s", item, yaml)
+				}
+				last = index
+			}
+			if strings.Index(yaml, "eth0:") > strings.Index(yaml, "eth1:") {
+				t.Errorf("Expected interfaces sorted by name, got:\n%s", yaml)
+			}
+		} else if yaml != first {
+			t.Fatalf("Output changed between runs:\n%s\nvs\n%s", first, yaml)
+		}
+	}
+}
+
+func TestTargetReleaseGateways(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.100/24, fd00::100/64",
+			Gateway4:  "192.168.1.1",
+			Gateway6:  "fd00::1",
+		}},
+		Renderer:      "networkd",
+		TargetRelease: "24.04",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	if strings.Contains(yaml, "gateway4:") || strings.Contains(yaml, "gateway6:") {
+		t.Errorf("Expected gateways to be converted for 24.04, got:\n%s", yaml)
+	}
+	for _, expected := range []string{"- to: default\n          via: 192.168.1.1", "- to: default\n          via: fd00::1"} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+		}
+	}
+	if len(config.Warnings) != 2 {
+		t.Errorf("Expected 2 conversion warnings, got %v", config.Warnings)
+	}
+
+	formData.TargetRelease = "20.04"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml = configToYAML(config)
+	if !strings.Contains(yaml, "gateway4: 192.168.1.1") || !strings.Contains(yaml, "gateway6: fd00::1") {
+		t.Errorf("Expected gateways to be kept for 20.04, got:\n%s", yaml)
+	}
+	if strings.Contains(yaml, "routes:") {
+		t.Errorf("Expected no routes for 20.04, got:\n%s", yaml)
+	}
+
+	formData.TargetRelease = "18.04"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected error for unsupported target release")
+	}
+}
+
+func TestTargetReleaseGatewayWithDefaultRoute(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "10.0.0.2/24",
+			Gateway4:  "10.0.0.1",
+			Routes:    "default 10.0.0.254",
+		}},
+		Renderer:      "networkd",
+		TargetRelease: "24.04",
+	}
+	
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "eth0: gateway 10.0.0.1 can't become a default route") {
+		t.Errorf("Expected an error for a second IPv4 default route, got %v", err)
+	}
+	
+	// An IPv6 default route doesn't conflict with the IPv4 gateway
+	formData.Interfaces[0].Addresses = "10.0.0.2/24, fd00::2/64"
+	formData.Interfaces[0].Routes = "default fd00::1"
+	if _, err := generateNetplanConfig(formData); err != nil {
+		t.Errorf("generateNetplanConfig failed: %v", err)
+	}
+}
+func TestNormalizeGateways(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.100/24", Routes: "default 192.168.1.1"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.0.2/24", Routes: "default 10.0.0.1 200"},
+		},
+		Renderer:      "NetworkManager",
+		TargetRelease: "20.04",
+	}
+	
+	// NetworkManager on 20.04 gets a plain default route as its gateway;
+	// one with a metric stays a route
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	eth0, eth1 := config.Network.Ethernets["eth0"], config.Network.Ethernets["eth1"]
+	if eth0.Gateway4 != "192.168.1.1" || eth0.Routes != nil {
+		t.Errorf("Expected eth0's default route as gateway4, got gateway4 %q, routes %+v", eth0.Gateway4, eth0.Routes)
+	}
+	if eth1.Gateway4 != "" || len(eth1.Routes) != 1 {
+		t.Errorf("Expected eth1's default route with a metric to stay a route, got gateway4 %q, routes %+v", eth1.Gateway4, eth1.Routes)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: default route via 192.168.1.1 converted to a gateway key") {
+		t.Errorf("Expected one conversion warning for eth0, got %v", config.Warnings)
+	}
+	
+	// networkd on 20.04 keeps the routes as given
+	formData.Renderer = "networkd"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if eth0 := config.Network.Ethernets["eth0"]; eth0.Gateway4 != "" || len(eth0.Routes) != 1 {
+		t.Errorf("Expected eth0's default route to be kept for networkd, got gateway4 %q, routes %+v", eth0.Gateway4, eth0.Routes)
+	}
+	
+	// 24.04 deprecates gateway keys for every renderer
+	formData.Renderer = "NetworkManager"
+	formData.TargetRelease = "24.04"
+	formData.Interfaces[0].Routes = ""
+	formData.Interfaces[0].Gateway4 = "192.168.1.1"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if eth0 := config.Network.Ethernets["eth0"]; eth0.Gateway4 != "" || !reflect.DeepEqual(eth0.Routes, []Route{{To: "default", Via: "192.168.1.1"}}) {
+		t.Errorf("Expected eth0's gateway as a default route for 24.04, got gateway4 %q, routes %+v", eth0.Gateway4, eth0.Routes)
+	}
+}
+
+func TestNameserversPerFamily(t *testing.T) {
+	tests := []struct {
+		name         string
+		nameservers4 string
+		nameservers6 string
+		expected     []string
+		wantErr      bool
+	}{
+		{"ipv4", "8.8.8.8, 1.1.1.1", "", []string{"1.1.1.3", "8.8.8.8", "1.1.1.1"}, false},
+		{"ipv6", "", "2001:4860:4860::8888", []string{"1.1.1.3", "2001:4860:4860::8888"}, false},
+		{"both", "8.8.8.8", "2606:4700:4700::1111", []string{"1.1.1.3", "8.8.8.8", "2606:4700:4700::1111"}, false},
+		{"ipv6 in ipv4 field", "2001:4860:4860::8888", "", nil, true},
+		{"ipv4 in ipv6 field", "", "8.8.8.8", nil, true},
+		{"not an address", "dns.example.com", "", nil, true},
+	}
+
+	for _, test := range tests {
+		iface := InterfaceDefinition{
+			Name:         "eth0",
+			Nameservers:  "1.1.1.3",
+			Nameservers4: test.nameservers4,
+			Nameservers6: test.nameservers6,
+		}
+
+		settings, err := parseInterfaceSettings(iface)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		got := settings.Nameservers.Addresses
+		if strings.Join(got, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: nameservers = %v, want %v", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestSearchDomains(t *testing.T) {
+	settings, err := parseInterfaceSettings(InterfaceDefinition{
+		Name:          "eth0",
+		SearchDomains: "example.com, corp.example.com.",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error for valid search domains: %v", err)
+	}
+	if got := strings.Join(settings.Nameservers.Search, ","); got != "example.com,corp.example.com." {
+		t.Errorf("search = %s, want example.com,corp.example.com.", got)
+	}
+	
+	config := &NetplanConfig{Network: NetworkConfig{Version: 2, Ethernets: map[string]EthernetConfig{}}}
+	eth := EthernetConfig{}
+	eth.setSettings(settings)
+	config.Network.Ethernets["eth0"] = eth
+	if yaml := configToYAML(config); !strings.Contains(yaml, "      nameservers:\n        search:\n          - example.com\n") {
+		t.Errorf("Expected a search list without addresses, got:\n%s", yaml)
+	}
+	
+	_, err = parseInterfaceSettings(InterfaceDefinition{Name: "eth0", SearchDomains: "example.com, bad domain.com"})
+	if err == nil || !strings.Contains(err.Error(), `"bad domain.com"`) {
+		t.Errorf("Expected an error naming the bad domain, got %v", err)
+	}
+}
+
+func TestNameserverOrder(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:        "ethernet",
+			Name:        "eth0",
+			UseStatic:   true,
+			Addresses:   "192.168.1.10/24, 2001:db8::10/64",
+			Nameservers: "2001:4860:4860::8888, 8.8.8.8, 2606:4700:4700::1111, 1.1.1.1",
+		}},
+		Renderer: "networkd",
+	}
+	
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{"", []string{"2001:4860:4860::8888", "8.8.8.8", "2606:4700:4700::1111", "1.1.1.1"}},
+		{"ipv4-first", []string{"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888", "2606:4700:4700::1111"}},
+		{"ipv6-first", []string{"2001:4860:4860::8888", "2606:4700:4700::1111", "8.8.8.8", "1.1.1.1"}},
+	}
+	for _, test := range tests {
+		formData.NameserverOrder = test.order
+		config, err := generateNetplanConfig(formData)
+		if err != nil {
+			t.Fatalf("%q: generateNetplanConfig failed: %v", test.order, err)
+		}
+		if got := config.Network.Ethernets["eth0"].Nameservers.Addresses; !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected nameservers %v, got %v", test.order, test.expected, got)
+		}
+	}
+	
+	formData.NameserverOrder = "family"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "invalid nameserver order") {
+		t.Errorf("Expected an invalid nameserver order error, got %v", err)
+	}
+}
+
+func TestClearNameservers(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", DHCP4Overrides: "use-dns=false", ClearNameservers: true},
+			{Type: "ethernet", Name: "eth1"},
+		},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, "    eth0:\n      dhcp4: true\n      nameservers:\n        addresses: []\n") {
+		t.Errorf("Expected an explicitly empty nameservers list, got:\n%s", yaml)
+	}
+	if strings.Count(yaml, "nameservers:") != 1 {
+		t.Errorf("Expected no nameservers for eth1, got:\n%s", yaml)
+	}
+	
+	// The empty list survives a round trip and the generic tree
+	parsed, err := parseNetplanYAML(yaml)
+	if err != nil {
+		t.Fatalf("Failed to parse the YAML: %v", err)
+	}
+	if ns := parsed.Network.Ethernets["eth0"].Nameservers; ns == nil || ns.Addresses == nil || len(ns.Addresses) != 0 {
+		t.Errorf("Expected an empty, non-nil address list after parsing, got %+v", ns)
+	}
+	if !strings.Contains(treeToYAML(configToTree(config)), "        addresses: []\n") {
+		t.Errorf("Expected addresses: [] in the tree output")
+	}
+	
+	formData.Interfaces[0].Nameservers = "1.1.1.1"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected an error for nameservers that are both cleared and given")
+	}
+}
+
+func TestCriticalOptionalWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:     "ethernet",
+				Name:     "eth0",
+				Critical: true,
+				Optional: true,
+			},
+			{
+				Type:     "ethernet",
+				Name:     "eth1",
+				Optional: true,
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0") {
+		t.Errorf("Expected one critical/optional warning naming eth0, got %v", config.Warnings)
+	}
+
+	yaml := configToYAML(config)
+	if !strings.Contains(yaml, "critical: true") || !strings.Contains(yaml, "optional: true") {
+		t.Errorf("Expected critical and optional in YAML, got:\n%s", yaml)
+	}
+}
+
+func TestParseIntValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		units    map[string]int
+		expected int
+		wantErr  bool
+	}{
+		{"9000", mtuUnits, 9000, false},
+		{"9000b", mtuUnits, 9000, false},
+		{"1500 bytes", mtuUnits, 1500, false},
+		{"100", intervalUnits, 100, false},
+		{"100ms", intervalUnits, 100, false},
+		{"1s", intervalUnits, 1000, false},
+		{"2", countUnits, 2, false},
+		{"2s", countUnits, 0, true},
+		{"9000kb", mtuUnits, 0, true},
+		{"jumbo", mtuUnits, 0, true},
+		{"", intervalUnits, 0, true},
+		{"-5", countUnits, 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := parseIntValue(test.input, test.units)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseIntValue(%q) = %d, expected error", test.input, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseIntValue(%q) failed: %v", test.input, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("parseIntValue(%q) = %d, want %d", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestSuffixedMTUAndBondIntervals(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:           "bond",
+			Name:           "bond0",
+			BondInterfaces: "eth0,eth1",
+			BondMode:       "active-backup",
+			BondMIIMonitor: "100ms",
+			BondUpDelay:    "1s",
+			MTU:            "9000b",
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	for _, expected := range []string{"mtu: 9000", "mii-monitor-interval: 100", "up-delay: 1000"} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+		}
+	}
+
+	formData.Interfaces[0].MTU = "big"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected error for garbage MTU")
+	}
+}
+
+func TestMemberStubsDisableBothDHCP(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:           "bond",
+				Name:           "bond0",
+				BondInterfaces: "eth0,eth1",
+				BondMode:       "active-backup",
+			},
+			{
+				Type:             "bridge",
+				Name:             "br0",
+				BridgeInterfaces: "eth2",
+				AllowMemberIPv6:  true,
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	for _, name := range []string{"eth0", "eth1"} {
+		eth := config.Network.Ethernets[name]
+		if eth.DHCP4 == nil || *eth.DHCP4 || eth.DHCP6 == nil || *eth.DHCP6 {
+			t.Errorf("Expected %s to have dhcp4: false and dhcp6: false, got %+v", name, eth)
+		}
+	}
+
+	eth2 := config.Network.Ethernets["eth2"]
+	if eth2.DHCP4 == nil || *eth2.DHCP4 || eth2.DHCP6 != nil {
+		t.Errorf("Expected eth2 to have dhcp4: false and dhcp6 unset, got %+v", eth2)
+	}
+}
+
+func TestOnLinkRoute(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:      "ethernet",
+				Name:      "eth0",
+				UseStatic: true,
+				Addresses: "203.0.113.10/32",
+				Routes:    "default 10.255.255.1 on-link",
+				RoutesCSV: "to,via,on-link\n198.51.100.0/24,10.255.255.2,true",
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	routes := config.Network.Ethernets["eth0"].Routes
+	if len(routes) != 2 || !routes[0].OnLink || !routes[1].OnLink {
+		t.Fatalf("Expected two on-link routes, got %+v", routes)
+	}
+
+	yaml := configToYAML(config)
+	expected := "        - to: default\n          via: 10.255.255.1\n          on-link: true\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+
+	for _, input := range []string{"10.0.0.0/8 on-link", "default on-link"} {
+		if _, err := parseRoutes(input); err == nil {
+			t.Errorf("Expected an error for on-link route without via: %q", input)
+		}
+	}
+	if _, err := parseRoutesCSV("to,on-link\n10.0.0.0/8,true"); err == nil {
+		t.Error("Expected an error for CSV on-link route without via")
+	}
+}
+
+func TestIPv6OnlyInterface(t *testing.T) {
+	tests := []struct {
+		name  string
+		iface InterfaceDefinition
+		dhcp6 bool
+	}{
+		{
+			name:  "dhcp6",
+			iface: InterfaceDefinition{Type: "ethernet", Name: "eth0", IPv6Only: true},
+			dhcp6: true,
+		},
+		{
+			name: "static v6 addresses",
+			iface: InterfaceDefinition{
+				Type:      "ethernet",
+				Name:      "eth0",
+				UseStatic: true,
+				Addresses: "2001:db8::10/64",
+				Gateway6:  "2001:db8::1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formData := FormData{Interfaces: []InterfaceDefinition{tt.iface}, Renderer: "networkd"}
+			config, err := generateNetplanConfig(formData)
+			if err != nil {
+				t.Fatalf("generateNetplanConfig failed: %v", err)
+			}
+
+			yaml := configToYAML(config)
+			if strings.Contains(yaml, "dhcp4:") {
+				t.Errorf("Expected no dhcp4 line for an IPv6-only interface, got:\n%s", yaml)
+			}
+			if tt.dhcp6 != strings.Contains(yaml, "dhcp6: true") {
+				t.Errorf("Expected dhcp6: true to be %v, got:\n%s", tt.dhcp6, yaml)
+			}
+		})
+	}
+
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			IPv6Only:  true,
+			Addresses: "192.168.1.10/24",
+		}},
+		Renderer: "networkd",
+	}
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Error("Expected an error for IPv6 only with an IPv4 address")
+	}
+}
+
+func TestIPv6OnlyServer(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:          "ethernet",
+			Name:          "eth0",
+			UseStatic:     true,
+			IPv6Only:      true,
+			Addresses:     "fd00:10::10/64, fd00:10::11/64",
+			Routes:        "default fd00:10::1",
+			Nameservers:   "2606:4700:4700::1111, 2001:4860:4860::8888",
+			SearchDomains: "example.com",
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	want := `network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    eth0:
+      addresses:
+        - fd00:10::10/64
+        - fd00:10::11/64
+      routes:
+        - to: default
+          via: fd00:10::1
+      nameservers:
+        addresses:
+          - 2606:4700:4700::1111
+          - 2001:4860:4860::8888
+        search:
+          - example.com
+`
+	if yaml := configToYAML(config); yaml != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, yaml)
+	}
+	if len(config.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", config.Warnings)
+	}
+	
+	// The family-neutral inputs can't sneak IPv4 in
+	formData.Interfaces[0].Nameservers = "2606:4700:4700::1111, 1.1.1.1"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "eth0 is IPv6 only but has IPv4 nameservers: 1.1.1.1") {
+		t.Errorf("Expected an IPv4 nameserver error, got %v", err)
+	}
+	formData.Interfaces[0].Nameservers = ""
+	formData.Interfaces[0].Routes = "default fd00:10::1\n10.0.0.0/8 fd00:10::2"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "eth0 is IPv6 only but has an IPv4 route to 10.0.0.0/8") {
+		t.Errorf("Expected an IPv4 route error, got %v", err)
+	}
+}
+
+func TestDummyDevice(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:      "dummy",
+				Name:      "dummy0",
+				Addresses: "10.10.10.1/32",
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	if _, exists := config.Network.Ethernets["dummy0"]; exists {
+		t.Error("dummy0 should not be declared as an ethernet")
+	}
+
+	yaml := configToYAML(config)
+	expected := "  dummy-devices:\n    dummy0:\n      dhcp4: false\n      addresses:\n        - 10.10.10.1/32\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+
+	formData.Interfaces[0].Addresses = ""
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Error("Expected an error for a dummy device without addresses")
+	}
+}
+
+func TestModem(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:        "modem",
+				Name:        "cdc-wdm0",
+				ModemAPN:    "internet",
+				ModemNumber: "*99#",
+				ModemPIN:    "0000",
+			},
+		},
+		Renderer: "NetworkManager",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", config.Warnings)
+	}
+
+	yaml := configToYAML(config)
+	expected := "  modems:\n    cdc-wdm0:\n      apn: internet\n      number: \"*99#\"\n      pin: \"0000\"\n      dhcp4: true\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+
+	formData.Interfaces[0].ModemAPN = ""
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Error("Expected an error for a modem without APN or auto-config")
+	}
+}
+
+func TestNMDevice(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:          "nm-device",
+				Name:          "wg0",
+				NMPassthrough: "connection.type=wireguard\nwireguard.listen-port=51820",
+			},
+		},
+		Renderer: "NetworkManager",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	expected := "  nm-devices:\n    wg0:\n      networkmanager:\n        passthrough:\n          connection.type: wireguard\n          wireguard.listen-port: \"51820\"\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+
+	for _, passthrough := range []string{"", "\n  \n", "type=wireguard"} {
+		formData.Interfaces[0].NMPassthrough = passthrough
+		if _, err := generateNetplanConfig(formData); err == nil {
+			t.Errorf("Expected an error for passthrough %q", passthrough)
+		}
+	}
+}
+
+func TestBondAllMembersActive(t *testing.T) {
+	allActive := true
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:           "bond",
+				Name:           "bond0",
+				BondInterfaces: "eth0,eth1",
+				BondMode:       "active-backup",
+				BondAllActive:  &allActive,
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	yaml := configToYAML(config)
+	expected := "        mode: active-backup\n        all-members-active: true\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+
+	formData.Interfaces[0].BondAllActive = nil
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if yaml := configToYAML(config); strings.Contains(yaml, "all-members-active") {
+		t.Errorf("Expected no all-members-active when unset, got:\n%s", yaml)
+	}
+}
+
+func TestGatewayFamilies(t *testing.T) {
+	tests := []struct {
+		name     string
+		gateway4 string
+		gateway6 string
+		wantErr  bool
+	}{
+		{"correct", "192.168.1.1", "2001:db8::1", false},
+		{"swapped", "2001:db8::1", "192.168.1.1", true},
+		{"ipv6 as gateway4", "2001:db8::1", "", true},
+		{"ipv4 as gateway6", "", "192.168.1.1", true},
+		{"not an address", "gateway", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formData := FormData{
+				Interfaces: []InterfaceDefinition{{
+					Type:      "ethernet",
+					Name:      "eth0",
+					UseStatic: true,
+					Addresses: "192.168.1.10/24, 2001:db8::10/64",
+					Gateway4:  tt.gateway4,
+					Gateway6:  tt.gateway6,
+				}},
+				Renderer: "networkd",
+			}
+			_, err := generateNetplanConfig(formData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBondMACAddress(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:           "bond",
+				Name:           "bond0",
+				BondInterfaces: "eth0,eth1",
+				BondMode:       "active-backup",
+				MACAddress:     "02:00:00:00:00:01",
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	if mac := config.Network.Bonds["bond0"].MACAddress; mac != "02:00:00:00:00:01" {
+		t.Errorf("Expected bond0 macaddress 02:00:00:00:00:01, got %q", mac)
+	}
+	for _, member := range []string{"eth0", "eth1"} {
+		if mac := config.Network.Ethernets[member].MACAddress; mac != "" {
+			t.Errorf("Expected %s to have no macaddress, got %q", member, mac)
+		}
+	}
+
+	yaml := configToYAML(config)
+	expected := "        mode: active-backup\n      macaddress: \"02:00:00:00:00:01\"\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+}
+
+func TestBridgeMACAddress(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:             "bridge",
+				Name:             "br0",
+				BridgeInterfaces: "eth0",
+				MACAddress:       "02:00:00:00:00:02",
+			},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	yaml := configToYAML(config)
+	expected := "        - eth0\n      macaddress: \"02:00:00:00:00:02\"\n"
+	if !strings.Contains(yaml, expected) {
+		t.Errorf("Expected YAML to contain %q, got:\n%s", expected, yaml)
+	}
+}
+
+// failingWriter accepts limit bytes and then fails every write
+type failingWriter struct {
+	limit int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		return 0, errors.New("disk full")
+	}
+	f.limit -= len(p)
+	return len(p), nil
+}
+
+func TestWriteConfigYAML(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2", BondMode: "802.3ad"},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeConfigYAML(&buf, config); err != nil {
+		t.Fatalf("writeConfigYAML failed: %v", err)
+	}
+	if buf.String() != configToYAML(config) {
+		t.Errorf("Expected streamed YAML to match configToYAML, got:\n%s\nwant:\n%s", buf.String(), configToYAML(config))
+	}
+
+	if err := writeConfigYAML(&failingWriter{limit: 20}, config); err == nil {
+		t.Error("Expected writeConfigYAML to return the write error")
+	}
+}
+
+func BenchmarkIndexTemplatePerRequest(b *testing.B) {
+	data := PageData{FormData: FormData{Renderer: "networkd"}}
+	for i := 0; i < b.N; i++ {
+		tmpl, err := template.ParseFS(templateFS, "templates/index.html")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var buf bytes.Buffer
+		tmpl.Execute(&buf, data)
+	}
+}
+
+func BenchmarkIndexTemplateCached(b *testing.B) {
+	data := PageData{FormData: FormData{Renderer: "networkd"}}
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		indexTemplate.Execute(&buf, data)
+	}
+}
+
+func TestDedupeRoutes(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			Routes:    "10.0.0.0/8 192.168.1.254 100\n10.0.0.0/8 192.168.1.254 100\n10.0.0.0/8 192.168.1.253 200",
+			RoutesCSV: "to,via,metric\n10.0.0.0/8,192.168.1.254,100",
+		}},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	expected := []Route{
+		{To: "10.0.0.0/8", Via: "192.168.1.254", Metric: 100},
+		{To: "10.0.0.0/8", Via: "192.168.1.253", Metric: 200},
+	}
+	routes := config.Network.Ethernets["eth0"].Routes
+	if len(routes) != len(expected) {
+		t.Fatalf("Expected %d routes after dedup, got %+v", len(expected), routes)
+	}
+	for i, route := range routes {
+		if route != expected[i] {
+			t.Errorf("Route %d: expected %+v, got %+v", i, expected[i], route)
+		}
+	}
+
+	formData.Interfaces[0].Routes = "10.0.0.0/8 192.168.1.254 100\n10.0.0.0/8 192.168.1.253 100"
+	formData.Interfaces[0].RoutesCSV = ""
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "conflicting routes") {
+		t.Errorf("Expected a conflicting routes error, got %v", err)
+	}
+	
+	formData.Interfaces[0].Routes = "10.1.0.0/16 192.168.1.254\n10.1.0.0/16 192.168.1.254 mtu 1400"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), `conflicting routes to 10.1.0.0/16 with metric 0 via "192.168.1.254": they differ in mtu or on-link`) {
+		t.Errorf("Expected a conflicting route settings error, got %v", err)
+	}
+}
+
+func TestDualStackDefaultRoutes(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "10.0.0.2/24, fd00::2/64",
+			Routes:    "default 10.0.0.1\ndefault fd00::1",
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("Expected an IPv4 and an IPv6 default route to be accepted: %v", err)
+	}
+	if routes := config.Network.Ethernets["eth0"].Routes; len(routes) != 2 {
+		t.Errorf("Expected both default routes, got %+v", routes)
+	}
+}
+
+func TestAcceptRAWarning(t *testing.T) {
+	acceptRA := false
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:     "ethernet",
+				Name:     "eth0",
+				DHCP6:    true,
+				AcceptRA: &acceptRA,
+			},
+			{
+				Type:      "ethernet",
+				Name:      "eth1",
+				UseStatic: true,
+				Addresses: "fd00::10/64",
+				Gateway6:  "fd00::1",
+				AcceptRA:  &acceptRA,
+			},
+		},
+		Renderer: "networkd",
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: dhcp6") {
+		t.Errorf("Expected one dhcp6/accept-ra warning for eth0, got %v", config.Warnings)
+	}
+
+	formData.Interfaces[1].Gateway6 = ""
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 3 || !strings.Contains(config.Warnings[1], "eth1: has IPv6 addresses but no IPv6 gateway") {
+		t.Errorf("Expected a missing IPv6 gateway warning for eth1, got %v", config.Warnings)
+	}
+}
+
+func TestEmbedSource(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			Routes:    "10.0.0.0/8 192.168.1.254\n172.16.0.0/12 192.168.1.253",
+		}},
+		Renderer:    "networkd",
+		EmbedSource: true,
+	}
+
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0","useStatic":true,"addresses":"192.168.1.10/24",` +
+		`"routes":"10.0.0.0/8 192.168.1.254\n172.16.0.0/12 192.168.1.253"}],"renderer":"networkd","embedSource":true}`
+	req := httptest.NewRequest("POST", "/generate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleGenerate(w, req)
+
+	var response struct {
+		YAML string `json:"yaml"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The source is on comment lines, so the YAML still parses to the same config
+	for _, line := range strings.Split(strings.TrimSpace(response.YAML), "\n") {
+		if strings.Contains(line, "\"interfaces\"") && !strings.HasPrefix(line, "#") {
+			t.Errorf("Expected the embedded source to be commented, got line %q", line)
+		}
+	}
+	if _, err := parseNetplanYAML(response.YAML); err != nil {
+		t.Errorf("Expected YAML with embedded source to parse, got %v", err)
+	}
+
+	source, err := extractSource(response.YAML)
+	if err != nil || source == nil {
+		t.Fatalf("extractSource failed: %v", err)
+	}
+	if !reflect.DeepEqual(*source, formData) {
+		t.Errorf("Expected embedded source %+v, got %+v", formData, *source)
+	}
+
+	if source, err := extractSource(configToYAML(&NetplanConfig{})); source != nil || err != nil {
+		t.Errorf("Expected no source in plain YAML, got %+v, %v", source, err)
+	}
+}
+
+func TestGenerateEncodedFormats(t *testing.T) {
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0","useStatic":true,"addresses":"192.168.1.10/24"}],"renderer":"networkd"}`
+	var formData FormData
+	if err := json.Unmarshal([]byte(body), &formData); err != nil {
+		t.Fatalf("Invalid test body: %v", err)
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	expected := renderYAML(config, formData)
+	
+	generate := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/generate?format="+format, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handleGenerate(w, req)
+		return w
+	}
+	
+	w := generate("escaped")
+	if line := strings.TrimSpace(w.Body.String()); strings.Contains(line, "\n") || !strings.Contains(line, `\n`) {
+		t.Errorf("Expected a single line with escaped newlines, got %q", w.Body.String())
+	}
+	var escaped string
+	if err := json.NewDecoder(w.Body).Decode(&escaped); err != nil || escaped != expected {
+		t.Errorf("Expected escaped YAML to decode to:\n%s\nGot %q, %v", expected, escaped, err)
+	}
+	
+	w = generate("base64")
+	decoded, err := base64.StdEncoding.DecodeString(w.Body.String())
+	if err != nil || string(decoded) != expected {
+		t.Errorf("Expected base64 YAML to decode to:\n%s\nGot %q, %v", expected, decoded, err)
+	}
+	
+	if w = generate("xml"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown format") {
+		t.Errorf("Expected an unknown format error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLegacyFormCommonFields(t *testing.T) {
+	form := url.Values{}
+	form.Set("interface_type", "ethernet")
+	form.Set("interface_name", "eth0")
+	form.Set("macaddress", "stable")
+	form.Set("mtu", "9000")
+	form.Set("optional", "on")
+	form.Set("renderer", "networkd")
+
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	handleGenerate(rec, req)
+
+	page := rec.Body.String()
+	for _, expected := range []string{"macaddress: stable", "optional: true", "mtu: 9000", "dhcp4: true"} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected rendered page to contain %q", expected)
+		}
+	}
+
+	// Forms without the new fields behave as before
+	form = url.Values{}
+	form.Set("interface_type", "ethernet")
+	form.Set("interface_name", "eth0")
+	form.Set("renderer", "networkd")
+
+	req = httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+
+	handleGenerate(rec, req)
+
+	page = rec.Body.String()
+	start := strings.Index(page, `id="output">`)
+	if start < 0 {
+		t.Fatal("Expected the rendered page to have an output area")
+	}
+	output := page[start : start+strings.Index(page[start:], "</div>")]
+	if strings.Contains(output, "mtu:") || strings.Contains(output, "optional:") || !strings.Contains(output, "dhcp4: true") {
+		t.Errorf("Expected a plain DHCP config from a legacy form without new fields, got %s", output)
+	}
+}
+
+func TestIndentWidth(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			Routes:    "10.0.0.0/8 192.168.1.254 100",
+		}},
+		Renderer:    "networkd",
+		IndentWidth: 4,
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("Failed to generate config: %v", err)
+	}
+	yaml := configToYAML(config)
+	
+	for _, line := range []string{
+		"network:\n    version: 2\n",
+		"\n    ethernets:\n        eth0:\n            dhcp4: false\n",
+		"\n            addresses:\n                - 192.168.1.10/24\n",
+		"\n            routes:\n                -   to: 10.0.0.0/8\n                    via: 192.168.1.254\n                    metric: 100\n",
+	} {
+		if !strings.Contains(yaml, line) {
+			t.Errorf("Expected 4-space indentation %q in:\n%s", line, yaml)
+		}
+	}
+	
+	parsed, err := parseNetplanYAML(yaml)
+	if err != nil {
+		t.Fatalf("Failed to parse 4-space YAML: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Network, config.Network) {
+		t.Errorf("4-space YAML parsed to %+v, want %+v", parsed.Network, config.Network)
+	}
+	
+	formData.IndentWidth = 3
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Error("Expected an error for an indent width of 3")
+	}
+}
+
+func TestSectionRenderers(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "modem", Name: "cdc-wdm0", ModemAPN: "internet"},
+		},
+		Renderer:         "networkd",
+		SectionRenderers: map[string]string{"modems": "NetworkManager", "ethernets": "networkd"},
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("Failed to generate config: %v", err)
+	}
+	if len(config.Warnings) != 0 {
+		t.Errorf("Expected no renderer warning for modems using NetworkManager, got %v", config.Warnings)
+	}
+	
+	yaml := configToYAML(config)
+	for _, section := range []string{
+		"  ethernets:\n    renderer: networkd\n    eth0:\n",
+		"  modems:\n    renderer: NetworkManager\n    cdc-wdm0:\n",
+	} {
+		if !strings.Contains(yaml, section) {
+			t.Errorf("Expected section renderer %q in:\n%s", section, yaml)
+		}
+	}
+	if yaml != treeToYAML(configToTree(config)) {
+		t.Errorf("Tree YAML differs from written YAML:\n%s", treeToYAML(configToTree(config)))
+	}
+	
+	parsed, err := parseNetplanYAML(yaml)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Network, config.Network) {
+		t.Errorf("Parsed %+v, want %+v", parsed.Network, config.Network)
+	}
+	
+	for _, renderers := range []map[string]string{
+		{"wifis": "NetworkManager"},
+		{"ethernets": "systemd"},
+	} {
+		formData.SectionRenderers = renderers
+		if _, err := generateNetplanConfig(formData); err == nil {
+			t.Errorf("Expected an error for section renderers %v", renderers)
+		}
+	}
+}
+
+func TestOverlappingSubnetsWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "192.168.1.20/24, 10.0.0.1/8", Gateway4: "192.168.1.1"},
+			{Type: "ethernet", Name: "eth2", UseStatic: true, Addresses: "192.168.2.1/24", Gateway4: "192.168.2.254"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0 and eth1 have addresses in overlapping subnets") {
+		t.Errorf("Expected one overlapping subnet warning for eth0 and eth1, got %v", config.Warnings)
+	}
+	
+	formData.Interfaces[1].Addresses = "192.168.0.1/16"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 2 {
+		t.Errorf("Expected a /16 to overlap both /24s, got %v", config.Warnings)
+	}
+}
+
+func TestLinkLocalAddressWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "fe80::1/64, fd00::1/64", Gateway6: "fd00::ff"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "169.254.1.1/16", Gateway4: "169.254.0.1"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: fe80::1/64 is an IPv6 link-local address") {
+		t.Errorf("Expected one link-local warning for fe80::1/64, got %v", config.Warnings)
+	}
+}
+
+func TestSpecialPurposeAddressWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.0.2.1/24", Gateway4: "192.0.2.254"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.0.5/24, fd00::5/64", Gateway4: "10.0.0.1"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: 192.0.2.1/24 is in the documentation range 192.0.2.0/24") {
+		t.Errorf("Expected one documentation range warning for eth0, got %v", config.Warnings)
+	}
+}
+
+func TestSendHostnameWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", DHCP4Overrides: "send-hostname=false, hostname=node1"},
+			{Type: "ethernet", Name: "eth1", DHCP4Overrides: "send-hostname=true, hostname=node2"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: dhcp4-overrides set a hostname with send-hostname: false") {
+		t.Errorf("Expected one send-hostname warning for eth0, got %v", config.Warnings)
+	}
+}
+
+func TestReservedNameWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "lo"},
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "lo: name is the loopback interface") {
+		t.Errorf("Expected one reserved name warning for lo, got %v", config.Warnings)
+	}
+}
+
+func TestVLANMTU(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", MTU: "1500"},
+			{Type: "vlan", Name: "vlan10", VLANID: "10", VLANLink: "eth0", MTU: "9000", UseStatic: true, Addresses: "10.10.0.2/24", Gateway4: "10.10.0.1"},
+			{Type: "ethernet", Name: "eth1", MTU: "9000"},
+			{Type: "vlan", Name: "vlan20", VLANID: "20", VLANLink: "eth1", MTU: "8996", UseStatic: true, Addresses: "10.20.0.2/24", Gateway4: "10.20.0.1"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "vlan10: MTU 9000 is larger than the MTU 1500 of its link eth0") {
+		t.Errorf("Expected one MTU warning for vlan10, got %v", config.Warnings)
+	}
+	
+	expected := `  vlans:
+    vlan10:
+      id: 10
+      link: eth0
+      mtu: 9000
+`
+	if yaml := configToYAML(config); !strings.Contains(yaml, expected) {
+		t.Errorf("Expected:\n%s\nin:\n%s", expected, yaml)
+	}
+	
+	formData.Interfaces[1].VLANID = "4095"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "invalid VLAN ID for vlan10") {
+		t.Errorf("Expected an invalid VLAN ID error, got %v", err)
+	}
+}
+
+func TestBondMemberTypes(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,br0", BondMode: "active-backup"},
+			{Type: "bridge", Name: "br0", BridgeInterfaces: "eth1"},
+		},
+		Renderer: "networkd",
+	}
+	
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "bond bond0 member br0 is a bridge") {
+		t.Errorf("Expected an error for the bridge member, got %v", err)
+	}
+	
+	// VLANs are valid members and don't get an ethernet declaration
+	formData.Interfaces = []InterfaceDefinition{
+		{Type: "bond", Name: "bond0", BondInterfaces: "eth0,vlan10", BondMode: "active-backup"},
+		{Type: "ethernet", Name: "eth1"},
+		{Type: "vlan", Name: "vlan10", VLANID: "10", VLANLink: "eth1"},
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if _, ok := config.Network.Ethernets["vlan10"]; ok {
+		t.Errorf("Expected no ethernet declaration for the VLAN member, got %+v", config.Network.Ethernets)
+	}
+}
+
+func TestMissingDefaultRouteWarning(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.0.2/24", Routes: "default 10.0.0.1"},
+			{Type: "ethernet", Name: "eth2", UseStatic: true, Addresses: "172.16.0.2/24", Gateway4: "172.16.0.1"},
+			{Type: "dummy", Name: "dummy0", Addresses: "10.10.10.1/32"},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: has static addresses but no gateway or default route") {
+		t.Errorf("Expected one missing default route warning for eth0, got %v", config.Warnings)
+	}
+}
+
+func TestBridgeWithoutMembers(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "bridge",
+			Name:      "br-host",
+			UseStatic: true,
+			Addresses: "10.99.0.1/24",
+		}},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("Expected a host-only bridge with addresses to be accepted, got %v", err)
+	}
+	yaml := configToYAML(config)
+	if strings.Contains(yaml, "interfaces:") || !strings.Contains(yaml, "    br-host:\n      dhcp4: false\n      addresses:\n        - 10.99.0.1/24\n") {
+		t.Errorf("Expected a bridge without an interfaces key, got:\n%s", yaml)
+	}
+	
+	formData.Interfaces[0].Addresses = ""
+	formData.Interfaces[0].UseStatic = false
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "needs member interfaces, or addresses") {
+		t.Errorf("Expected an error for a bridge without members or addresses, got %v", err)
+	}
+}
+
+func TestBondModeAliases(t *testing.T) {
+	for input, want := range map[string]string{
+		"lacp":          "802.3ad",
+		"802.3ad":       "802.3ad",
+		"active_backup": "active-backup",
+		"Active-Backup": "active-backup",
+		"roundrobin":    "balance-rr",
+		"balance_alb":   "balance-alb",
+		"4":             "802.3ad",
+	} {
+		config, err := generateNetplanConfig(FormData{
+			Interfaces: []InterfaceDefinition{{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: input}},
+			Renderer:   "networkd",
+		})
+		if err != nil {
+			t.Errorf("Mode %q: unexpected error %v", input, err)
+			continue
+		}
+		if got := config.Network.Bonds["bond0"].Parameters.Mode; got != want {
+			t.Errorf("Mode %q normalized to %q, want %q", input, got, want)
+		}
+	}
+	
+	for _, input := range []string{"lacp-fast", "7", "balance"} {
+		_, err := generateNetplanConfig(FormData{
+			Interfaces: []InterfaceDefinition{{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: input}},
+			Renderer:   "networkd",
+		})
+		if err == nil || !strings.Contains(err.Error(), "unknown bonding mode") {
+			t.Errorf("Mode %q: expected an unknown bonding mode error, got %v", input, err)
+		}
+	}
+}
+
+func TestRegisterInterfaceType(t *testing.T) {
+	// A fake type that adds a dummy device with a fixed address
+	registerInterfaceType("fake", func(config *NetplanConfig, iface InterfaceDefinition) error {
+		iface.Type = "dummy"
+		iface.Addresses = "10.255.0.1/32"
+		return addDummyToConfig(config, iface)
+	})
+	defer delete(typeHandlers, "fake")
+	
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{Type: "fake", Name: "fake0"}},
+		Renderer:   "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if want := "  dummy-devices:\n    fake0:\n      dhcp4: false\n      addresses:\n        - 10.255.0.1/32\n"; !strings.Contains(configToYAML(config), want) {
+		t.Errorf("Expected %q in:\n%s", want, configToYAML(config))
+	}
+	
+	formData.Interfaces[0].Type = "tunnel"
+	if _, err := generateNetplanConfig(formData); err == nil || err.Error() != "invalid interface type: tunnel" {
+		t.Errorf("Expected an invalid interface type error, got %v", err)
+	}
+}
+
+func TestMaxInterfaces(t *testing.T) {
+	defer func(limit int) { maxInterfaces = limit }(maxInterfaces)
+	maxInterfaces = 4
+	
+	var formData FormData
+	for i := 0; i < 5; i++ {
+		formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "ethernet", Name: fmt.Sprintf("eth%d", i)})
+	}
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "too many interfaces: 5 (at most 4 are allowed)") {
+		t.Errorf("Expected a too many interfaces error, got %v", err)
+	}
+	
+	formData.Interfaces = formData.Interfaces[:4]
+	if _, err := generateNetplanConfig(formData); err != nil {
+		t.Errorf("Expected 4 interfaces to be allowed, got %v", err)
+	}
+}
+
+func TestAllowedTypes(t *testing.T) {
+	defer func(types []string) { allowedTypes = types }(allowedTypes)
+	allowedTypes = []string{"ethernet"}
+	
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+		Renderer:   "networkd",
+	}
+	if _, err := generateNetplanConfig(formData); err != nil {
+		t.Errorf("Expected an ethernet to be allowed, got %v", err)
+	}
+	
+	formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2"})
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "interface type bond is not allowed for bond0") {
+		t.Errorf("Expected a not allowed error for the bond, got %v", err)
+	}
+}
+
+func TestBondModeDefault(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1"},
+			{Type: "bond", Name: "bond1", BondInterfaces: "eth2,eth3"},
+			{Type: "bond", Name: "bond2", BondInterfaces: "eth4,eth5", BondMode: "balance-rr"},
+		},
+		Renderer:        "networkd",
+		BondModeDefault: "lacp",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	for name, expected := range map[string]string{"bond0": "802.3ad", "bond1": "802.3ad", "bond2": "balance-rr"} {
+		if mode := config.Network.Bonds[name].Parameters.Mode; mode != expected {
+			t.Errorf("Expected %s to have mode %s, got %q", name, expected, mode)
+		}
+	}
+	
+	formData.BondModeDefault = "fastest"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "invalid bond mode default") {
+		t.Errorf("Expected an invalid bond mode default error, got %v", err)
+	}
+}
+
+func TestDefaultBondMode(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("DEFAULT_BOND_MODE", "lacp")
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if config.DefaultBondMode != "802.3ad" {
+		t.Fatalf("Expected DEFAULT_BOND_MODE lacp to become 802.3ad, got %q", config.DefaultBondMode)
+	}
+	defer func(mode string) { defaultBondMode = mode }(defaultBondMode)
+	defaultBondMode = config.DefaultBondMode
+	
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1"},
+			{Type: "bond", Name: "bond1", BondInterfaces: "eth2,eth3", BondMode: "active-backup"},
+		},
+		Renderer: "networkd",
+	}
+	generated, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if mode := generated.Network.Bonds["bond0"].Parameters.Mode; mode != "802.3ad" {
+		t.Errorf("Expected bond0 to fall back to 802.3ad, got %q", mode)
+	}
+	if mode := generated.Network.Bonds["bond1"].Parameters.Mode; mode != "active-backup" {
+		t.Errorf("Expected bond1 to keep active-backup, got %q", mode)
+	}
+	
+	w := httptest.NewRecorder()
+	handleIndex(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), `data-default-bond-mode="802.3ad"`) {
+		t.Errorf("Expected the form to preselect 802.3ad")
+	}
+}
+
+func TestBondAndBridgeDHCPModel(t *testing.T) {
+	dhcp4 := true
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:           "bond",
+				Name:           "bond0",
+				BondInterfaces: "eth0,eth1",
+				BondMode:       "802.3ad",
+				UseStatic:      true,
+				Addresses:      "10.0.0.2/24",
+				DHCP6:          true,
+			},
+			{
+				Type:             "bridge",
+				Name:             "br0",
+				BridgeInterfaces: "eth2",
+				UseStatic:        true,
+				Addresses:        "192.168.50.2/24",
+				DHCP4:            &dhcp4,
+			},
+		},
+		Renderer: "networkd",
+	}
+	
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	bond := config.Network.Bonds["bond0"]
+	if bond.DHCP4 == nil || *bond.DHCP4 || bond.DHCP6 == nil || !*bond.DHCP6 || len(bond.Addresses) != 1 {
+		t.Errorf("Expected bond0 with a static IPv4 address, dhcp4 false and dhcp6 true, got %+v", bond)
+	}
+	bridge := config.Network.Bridges["br0"]
+	if bridge.DHCP4 == nil || !*bridge.DHCP4 || len(bridge.Addresses) != 1 {
+		t.Errorf("Expected br0 to run DHCPv4 alongside its static address, got %+v", bridge)
+	}
+	if !strings.Contains(configToYAML(config), "    br0:\n      interfaces:\n        - eth2\n      dhcp4: true\n      addresses:\n        - 192.168.50.2/24\n") {
+		t.Errorf("Expected dhcp4 and addresses together on br0, got:\n%s", configToYAML(config))
+	}
+	
+	formData.Interfaces[1].IPv6Only = true
+	formData.Interfaces[1].Addresses = "2001:db8::2/64"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Error("Expected an error for dhcp4 on an IPv6 only bridge")
+	}
+}
+
+func TestRenderYAMLTrailingNewline(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+		Renderer:   "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	for _, embed := range []bool{false, true} {
+		formData.EmbedSource = embed
+		yaml := renderYAML(config, formData)
+		if !strings.HasSuffix(yaml, "\n") || strings.HasSuffix(yaml, "\n\n") {
+			t.Errorf("Expected exactly one trailing newline (embed source %t), got %q", embed, yaml)
+		}
+		if strings.HasPrefix(yaml, "\uFEFF") {
+			t.Errorf("Expected no byte order mark (embed source %t)", embed)
+		}
+		
+		formData.OmitTrailingNewline = true
+		if yaml := renderYAML(config, formData); strings.HasSuffix(yaml, "\n") {
+			t.Errorf("Expected no trailing newline when omitted (embed source %t), got %q", embed, yaml)
+		}
+		formData.OmitTrailingNewline = false
+	}
+}
+
+func TestMinimalOutput(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth2"},
+			{Type: "ethernet", Name: "eth3", UseStatic: true, Addresses: "10.0.0.2/24", Description: "storage"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: "active-backup"},
+		},
+		Renderer: "networkd",
+	}
+	full, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	formData.MinimalOutput = true
+	minimal, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	fullYAML, minimalYAML := configToYAML(full), configToYAML(minimal)
+	if strings.Count(fullYAML, "dhcp4: false") != 3 || strings.Count(fullYAML, "dhcp6: false") != 2 {
+		t.Errorf("Expected the full output to disable DHCP explicitly, got:\n%s", fullYAML)
+	}
+	if strings.Contains(minimalYAML, ": false") {
+		t.Errorf("Expected no default false values in the minimal output, got:\n%s", minimalYAML)
+	}
+	for _, want := range []string{"    eth0: {}\n", "    eth1: {}\n", "    eth2:\n      dhcp4: true\n", "    # storage\n    eth3:\n      addresses:\n", "    bond0:\n      interfaces:\n"} {
+		if !strings.Contains(minimalYAML, want) {
+			t.Errorf("Expected %q in the minimal output:\n%s", want, minimalYAML)
+		}
+	}
+	
+	// Both outputs must mean the same to netplan, where a missing dhcp4 or
+	// dhcp6 is false
+	parsed, err := parseNetplanYAML(minimalYAML)
+	if err != nil {
+		t.Fatalf("Failed to parse the minimal output: %v", err)
+	}
+	for name, eth := range full.Network.Ethernets {
+		got := parsed.Network.Ethernets[name]
+		if (eth.DHCP4 != nil && *eth.DHCP4) != (got.DHCP4 != nil && *got.DHCP4) || (eth.DHCP6 != nil && *eth.DHCP6) != (got.DHCP6 != nil && *got.DHCP6) {
+			t.Errorf("%s: DHCP changed from %+v to %+v", name, eth, got)
+		}
+	}
+}
+
+func TestExplicitDHCP(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2", UseStatic: true, Addresses: "10.0.0.2/24", Gateway4: "10.0.0.1"},
+		},
+		Renderer:      "networkd",
+		MinimalOutput: true,
+		Explicit:      true,
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	
+	yaml := configToYAML(config)
+	for _, want := range []string{"    eth0:\n      dhcp4: true\n      dhcp6: false\n", "    eth1:\n      dhcp4: false\n      dhcp6: false\n"} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected %q in the explicit output:\n%s", want, yaml)
+		}
+	}
+	if strings.Count(yaml, "dhcp4: ") != 4 || strings.Count(yaml, "dhcp6: ") != 4 {
+		t.Errorf("Expected dhcp4 and dhcp6 on all four interfaces, got:\n%s", yaml)
+	}
+}
+
+func TestDisabledInterface(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "ethernet", Name: "eth1", Disabled: true, UseStatic: true, Addresses: "10.0.0.2/24"},
+			// Disabled interfaces aren't validated unless they are commented out
+			{Type: "bond", Name: "bond0", Disabled: true},
+		},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	if strings.Contains(yaml, "eth1") || strings.Contains(yaml, "bond0") {
+		t.Errorf("Expected disabled interfaces to be left out, got:\n%s", yaml)
+	}
+	
+	formData.CommentDisabled = true
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "disabled interface bond0") {
+		t.Errorf("Expected an error for the invalid commented-out bond, got %v", err)
+	}
+	formData.Interfaces = formData.Interfaces[:2]
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml = configToYAML(config)
+	want := "# Disabled interfaces:\n#   ethernets:\n#     eth1:\n#       dhcp4: false\n#       addresses:\n#         - 10.0.0.2/24\n"
+	if !strings.Contains(yaml, want) {
+		t.Errorf("Expected the commented-out block %q, got:\n%s", want, yaml)
+	}
+	parsed, err := parseNetplanYAML(yaml)
+	if err != nil {
+		t.Fatalf("Failed to parse the YAML: %v", err)
+	}
+	if _, ok := parsed.Network.Ethernets["eth1"]; ok {
+		t.Errorf("Expected the commented-out eth1 to stay inactive")
+	}
+}
+
+func TestDHCPOverrideInputs(t *testing.T) {
+	useDNS := false
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:             "ethernet",
+			Name:             "eth0",
+			DHCP6:            true,
+			DHCP4Overrides:   "hostname=node1",
+			DHCP4RouteMetric: "100",
+			DHCP6UseDNS:      &useDNS,
+			DHCP6RouteMetric: "200",
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	for _, want := range []string{
+		"      dhcp4-overrides:\n        hostname: node1\n        route-metric: 100\n",
+		"      dhcp6-overrides:\n        route-metric: 200\n        use-dns: false\n",
+	} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected %q in:\n%s", want, yaml)
+		}
+	}
+	
+	formData.Interfaces[0].DHCP6Overrides = "route-metric=300"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "route-metric") {
+		t.Errorf("Expected an error for conflicting route metrics, got %v", err)
+	}
+	formData.Interfaces[0].DHCP6Overrides = "route-metric=200"
+	if _, err := generateNetplanConfig(formData); err != nil {
+		t.Errorf("Expected matching route metrics to be accepted, got %v", err)
+	}
+}
+
+func TestMemberStubsByRenderer(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: "active-backup"},
+			{Type: "bridge", Name: "br0", BridgeInterfaces: "eth2"},
+		},
+	}
+	
+	formData.Renderer = "networkd"
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	for _, member := range []string{"eth0", "eth1", "eth2"} {
+		if want := "    " + member + ":\n      dhcp4: false\n      dhcp6: false\n"; !strings.Contains(yaml, want) {
+			t.Errorf("networkd: expected %q in:\n%s", want, yaml)
+		}
+	}
+	
+	formData.Renderer = "NetworkManager"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml = configToYAML(config)
+	for _, member := range []string{"eth0", "eth1", "eth2"} {
+		if want := "    " + member + ": {}\n"; !strings.Contains(yaml, want) {
+			t.Errorf("NetworkManager: expected %q in:\n%s", want, yaml)
+		}
+	}
+	
+	// A section renderer for the ethernets decides over the global one
+	formData.SectionRenderers = map[string]string{"ethernets": "networkd"}
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if eth := config.Network.Ethernets["eth0"]; eth.DHCP4 == nil || *eth.DHCP4 {
+		t.Errorf("Expected dhcp4: false on a networkd member, got %+v", eth)
+	}
+}
+
+func TestStaticIPv4WithSLAAC(t *testing.T) {
+	acceptRA := true
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:      "ethernet",
+			Name:      "eth0",
+			UseStatic: true,
+			Addresses: "192.168.1.10/24",
+			Gateway4:  "192.168.1.1",
+			AcceptRA:  &acceptRA,
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if len(config.Warnings) > 0 {
+		t.Errorf("Expected no warnings, got %v", config.Warnings)
+	}
+	
+	yaml := configToYAML(config)
+	expected := `network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    eth0:
+      dhcp4: false
+      accept-ra: true
+      addresses:
+        - 192.168.1.10/24
+      gateway4: 192.168.1.1
+`
+	if yaml != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, yaml)
+	}
+	if strings.Contains(yaml, "dhcp6:") {
+		t.Errorf("Expected no dhcp6 line for SLAAC")
+	}
+}
+
+func TestSetNameCollision(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "nic1", MatchMAC: "52:54:00:00:00:01", SetName: "eth0"},
+			{Type: "ethernet", Name: "nic2", MatchMAC: "52:54:00:00:00:02", SetName: "lan0"},
+		},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if want := "    nic1:\n      match:\n        macaddress: \"52:54:00:00:00:01\"\n      set-name: eth0\n      dhcp4: true\n"; !strings.Contains(configToYAML(config), want) {
+		t.Errorf("Expected %q in:\n%s", want, configToYAML(config))
+	}
+	
+	formData.Interfaces[1].SetName = "eth0"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "nic1 and nic2 both set-name to eth0") {
+		t.Errorf("Expected a set-name collision error, got %v", err)
+	}
+	
+	formData.Interfaces[1] = InterfaceDefinition{Type: "ethernet", Name: "eth0"}
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "already the name of another ethernet") {
+		t.Errorf("Expected an error for renaming to an existing ethernet, got %v", err)
+	}
+	
+	formData.Interfaces = []InterfaceDefinition{{Type: "ethernet", Name: "nic1", SetName: "eth0"}}
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected an error for set-name without a match")
+	}
+}
+
+func TestSetNameClashesWithInterface(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "nic1", MatchMAC: "52:54:00:00:00:01", SetName: "uplink"},
+			{Type: "bridge", Name: "uplink", BridgeInterfaces: "eth1"},
+		},
+		Renderer: "networkd",
+	}
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "ethernet nic1 has set-name uplink, which is already the name of a bridge") {
+		t.Errorf("Expected a set-name clash error, got %v", err)
+	}
+}
+
+func TestStrictIfname(t *testing.T) {
+	defer func(strict bool) { strictIfname = strict }(strictIfname)
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "nic1", MatchMAC: "52:54:00:00:00:01", SetName: "storage-uplink-eth01"},
+		},
+		Renderer: "networkd",
+	}
+	
+	strictIfname = true
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "is longer than 15 characters") {
+		t.Errorf("Expected an error for a 20 character set-name, got %v", err)
+	}
+	
+	strictIfname = false
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if config.Network.Ethernets["nic1"].SetName != "storage-uplink-eth01" {
+		t.Errorf("Expected the long set-name to be kept, got %q", config.Network.Ethernets["nic1"].SetName)
+	}
+	if !strings.Contains(strings.Join(config.Warnings, "\n"), `set-name for nic1 "storage-uplink-eth01" is longer than 15 characters`) {
+		t.Errorf("Expected a warning for a 20 character set-name, got %v", config.Warnings)
+	}
+	
+	const long = "storage-uplink-nic01"
+	for _, form := range []FormData{
+		{Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: long}}},
+		{Interfaces: []InterfaceDefinition{{Type: "bond", Name: long, BondInterfaces: "eth0,eth1"}}},
+		{Interfaces: []InterfaceDefinition{{Type: "bridge", Name: long, BridgeInterfaces: "eth0"}}},
+		{Interfaces: []InterfaceDefinition{{Type: "vlan", Name: long, VLANID: "10", VLANLink: "eth0"}}},
+		{Interfaces: []InterfaceDefinition{{Type: "dummy", Name: long, UseStatic: true, Addresses: "10.0.0.1/32"}}},
+		{Interfaces: []InterfaceDefinition{{Type: "bond", Name: "bond0", BondInterfaces: long}}},
+		{Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: long}}, IdentifyByMAC: true, InterfaceMACs: map[string]string{long: "52:54:00:00:00:02"}},
+	} {
+		form.Renderer = "networkd"
+		what := form.Interfaces[0].Type + " " + form.Interfaces[0].Name
+		strictIfname = true
+		if _, err := generateNetplanConfig(form); err == nil || !strings.Contains(err.Error(), `"`+long+`" is longer than 15 characters`) {
+			t.Errorf("Expected an error for a 20 character name with %s, got %v", what, err)
+		}
+		
+		strictIfname = false
+		config, err := generateNetplanConfig(form)
+		if err != nil {
+			t.Fatalf("generateNetplanConfig failed for %s: %v", what, err)
+		}
+		if !strings.Contains(strings.Join(config.Warnings, "\n"), `"`+long+`" is longer than 15 characters`) {
+			t.Errorf("Expected a warning for a 20 character name with %s, got %v", what, config.Warnings)
+		}
+	}
+}
+
+func TestIdentifyByMAC(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.0.2/24", Gateway4: "10.0.0.1"},
+		},
+		Renderer:      "networkd",
+		IdentifyByMAC: true,
+		InterfaceMACs: map[string]string{"eth0": "52:54:00:00:00:01", "eth1": "52:54:00:AB:CD:02"},
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	for _, want := range []string{
+		"    eth0:\n      match:\n        macaddress: \"52:54:00:00:00:01\"\n      set-name: eth0\n",
+		"    eth1:\n      match:\n        macaddress: \"52:54:00:ab:cd:02\"\n      set-name: eth1\n",
+	} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected %q in:\n%s", want, yaml)
+		}
+	}
+	
+	delete(formData.InterfaceMACs, "eth1")
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "no MAC address given for ethernet eth1") {
+		t.Errorf("Expected a missing MAC error, got %v", err)
+	}
+}
+
+func TestMaintenanceInterface(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "10.0.0.2/24", Gateway4: "10.0.0.1"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "192.168.0.2/24", Gateway4: "192.168.0.1", Optional: true},
+		},
+		Renderer:             "networkd",
+		MaintenanceInterface: "eth1",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	eth1 := config.Network.Ethernets["eth1"]
+	if eth1.DHCP4 == nil || !*eth1.DHCP4 || eth1.Optional {
+		t.Errorf("Expected eth1 to use DHCP and not be optional, got %+v", eth1)
+	}
+	if eth0 := config.Network.Ethernets["eth0"]; eth0.DHCP4 != nil && *eth0.DHCP4 {
+		t.Errorf("Expected eth0 to keep its static addressing, got %+v", eth0)
+	}
+	if len(config.Warnings) != 2 || !strings.Contains(config.Warnings[0], "eth1: dhcp4 enabled") || !strings.Contains(config.Warnings[1], "eth1: optional removed") {
+		t.Errorf("Expected warnings for both overridden settings, got %v", config.Warnings)
+	}
+	
+	formData.MaintenanceInterface = "eth9"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "maintenance interface eth9 is not defined") {
+		t.Errorf("Expected an undefined maintenance interface error, got %v", err)
+	}
+	
+	formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "bond", Name: "bond0", BondInterfaces: "eth5,eth6"})
+	formData.MaintenanceInterface = "eth5"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "maintenance interface eth5 is a member of bond0") {
+		t.Errorf("Expected a bond member maintenance interface error, got %v", err)
+	}
+	
+	formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "nm-device", Name: "wg0", NMPassthrough: "connection.type=wireguard"})
+	formData.MaintenanceInterface = "wg0"
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "maintenance interface wg0 is an nm-device, whose settings are passed through") {
+		t.Errorf("Expected an nm-device maintenance interface error, got %v", err)
+	}
+}
+
+func TestBondJumboFrames(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:           "bond",
+			Name:           "bond0",
+			BondInterfaces: "eth0,eth1",
+			BondMode:       "802.3ad",
+			JumboFrames:    true,
+			UseStatic:      true,
+			Addresses:      "10.0.0.2/24",
+		}},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if mtu := config.Network.Bonds["bond0"].MTU; mtu != 9000 {
+		t.Errorf("Expected MTU 9000 on bond0, got %d", mtu)
+	}
+	for _, member := range []string{"eth0", "eth1"} {
+		if mtu := config.Network.Ethernets[member].MTU; mtu != 9000 {
+			t.Errorf("Expected MTU 9000 on %s, got %d", member, mtu)
+		}
+	}
+	
+	formData.Interfaces[0].MTU = "9000"
+	if _, err := generateNetplanConfig(formData); err != nil {
+		t.Errorf("Expected a matching MTU to be accepted, got %v", err)
+	}
+	formData.Interfaces[0].MTU = "1500"
+	if _, err := generateNetplanConfig(formData); err == nil {
+		t.Errorf("Expected an error for jumbo frames with MTU 1500")
+	}
+	formData.Interfaces[0].MTU = ""
+	
+	// A member defined after the bond replaces its stub, and one defined
+	// before it would be replaced by the stub; either way its MTU must match
+	member := InterfaceDefinition{Type: "ethernet", Name: "eth0", MTU: "1500"}
+	for _, interfaces := range [][]InterfaceDefinition{
+		{formData.Interfaces[0], member},
+		{member, formData.Interfaces[0]},
+	} {
+		_, err := generateNetplanConfig(FormData{Interfaces: interfaces, Renderer: "networkd"})
+		if err == nil || !strings.Contains(err.Error(), "bond bond0 has jumbo frames, which set the MTU to 9000, but its member eth0 has MTU 1500") {
+			t.Errorf("Expected a member MTU error for %s first, got %v", interfaces[0].Name, err)
+		}
+	}
+	
+	member.MTU = ""
+	config, err = generateNetplanConfig(FormData{Interfaces: []InterfaceDefinition{formData.Interfaces[0], member}, Renderer: "networkd"})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if mtu := config.Network.Ethernets["eth0"].MTU; mtu != 9000 {
+		t.Errorf("Expected a member defined after the bond to get MTU 9000, got %d", mtu)
+	}
+}
+
+func TestAllStatic(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "10.0.0.2/24", Gateway4: "10.0.0.1"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "10.0.1.2/24", DHCP6: true},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth2,eth3", UseStatic: true, Addresses: "10.0.2.2/24"},
+		},
+		Renderer:  "networkd",
+		AllStatic: true,
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if s.DHCP4 == nil || *s.DHCP4 || s.DHCP6 == nil || *s.DHCP6 {
+			t.Errorf("Expected DHCP off on %s, got dhcp4 %v dhcp6 %v", name, s.DHCP4, s.DHCP6)
+		}
+	})
+	
+	formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "ethernet", Name: "eth4"})
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "allStatic requires static addresses, which eth4 lacks") {
+		t.Errorf("Expected a missing address error for eth4, got %v", err)
+	}
+}
+
+func TestAllStaticVLANLinkAndModem(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "vlan", Name: "vlan10", VLANID: "10", VLANLink: "eth0", UseStatic: true, Addresses: "10.0.10.2/24"},
+		},
+		Renderer:  "networkd",
+		AllStatic: true,
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("Expected the VLAN link to need no addresses: %v", err)
+	}
+	if eth0 := config.Network.Ethernets["eth0"]; eth0.DHCP4 == nil || *eth0.DHCP4 {
+		t.Errorf("Expected DHCP off on the VLAN link, got %+v", eth0)
+	}
+	
+	formData.Interfaces = append(formData.Interfaces, InterfaceDefinition{Type: "modem", Name: "cdc-wdm0", ModemAPN: "internet"})
+	if _, err := generateNetplanConfig(formData); err == nil || !strings.Contains(err.Error(), "allStatic can't be used with modem cdc-wdm0") {
+		t.Errorf("Expected a modem error, got %v", err)
+	}
+}
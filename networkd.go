@@ -0,0 +1,299 @@
+/*
+Export of generated configs as systemd-networkd files
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleExportNetworkd generates the config for the posted FormData and
+// returns equivalent systemd-networkd files, keyed by file name
+func handleExportNetworkd(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	files, warnings := exportNetworkd(config)
+	response := map[string]interface{}{"files": files}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// iniFile builds the contents of an INI-style file, as used by both
+// systemd units and NetworkManager keyfiles. Sections without any keys
+// are left out.
+type iniFile struct {
+	sections []iniSection
+}
+
+type iniSection struct {
+	name string
+	keys [][2]string
+}
+
+// Section starts a new section; following Set calls add keys to it
+func (f *iniFile) Section(name string) {
+	f.sections = append(f.sections, iniSection{name: name})
+}
+
+func (f *iniFile) Set(key, value string) {
+	section := &f.sections[len(f.sections)-1]
+	section.keys = append(section.keys, [2]string{key, value})
+}
+
+// SetIn adds a key to the named section, starting the section if there
+// isn't one yet
+func (f *iniFile) SetIn(section, key, value string) {
+	for i := range f.sections {
+		if f.sections[i].name == section {
+			f.sections[i].keys = append(f.sections[i].keys, [2]string{key, value})
+			return
+		}
+	}
+	f.Section(section)
+	f.Set(key, value)
+}
+
+func (f *iniFile) String() string {
+	var sb strings.Builder
+	for _, section := range f.sections {
+		if len(section.keys) == 0 {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[" + section.name + "]\n")
+		for _, kv := range section.keys {
+			sb.WriteString(kv[0] + "=" + kv[1] + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// exportNetworkd translates the config into .network files for every
+// interface and .netdev files for bonds, bridges, VLANs and dummy devices, named
+// like the files netplan itself generates. Settings networkd files can't
+// express, such as modems and DHCP overrides, are reported as warnings.
+func exportNetworkd(config *NetplanConfig) (map[string]string, []string) {
+	files := make(map[string]string)
+	warnings := stripRendererSpecific(config, "networkd")
+
+	// Bond and bridge membership is declared on the member's .network
+	memberOf := make(map[string][2]string)
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		for _, member := range config.Network.Bonds[name].Interfaces {
+			memberOf[member] = [2]string{"Bond", name}
+		}
+	}
+	for _, name := range sortedKeys(config.Network.Bridges) {
+		for _, member := range config.Network.Bridges[name].Interfaces {
+			memberOf[member] = [2]string{"Bridge", name}
+		}
+	}
+
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		bond := config.Network.Bonds[name]
+		netdev := &iniFile{}
+		netdev.Section("NetDev")
+		netdev.Set("Name", name)
+		netdev.Set("Kind", "bond")
+		netdev.Section("Bond")
+		netdev.Set("Mode", bond.Parameters.Mode)
+		if bond.Parameters.MIIMonitorInterval != 0 {
+			netdev.Set("MIIMonitorSec", fmt.Sprintf("%dms", bond.Parameters.MIIMonitorInterval))
+		}
+		if bond.Parameters.UpDelay != 0 {
+			netdev.Set("UpDelaySec", fmt.Sprintf("%dms", bond.Parameters.UpDelay))
+		}
+		if bond.Parameters.DownDelay != 0 {
+			netdev.Set("DownDelaySec", fmt.Sprintf("%dms", bond.Parameters.DownDelay))
+		}
+		if bond.Parameters.MinLinks != 0 {
+			netdev.Set("MinLinks", fmt.Sprint(bond.Parameters.MinLinks))
+		}
+		if bond.Parameters.AllMembersActive != nil {
+			netdev.Set("AllSlavesActive", fmt.Sprint(*bond.Parameters.AllMembersActive))
+		}
+		files["10-netplan-"+name+".netdev"] = netdev.String()
+	}
+	for _, name := range sortedKeys(config.Network.Bridges) {
+		files["10-netplan-"+name+".netdev"] = networkdNetdev(name, "bridge")
+	}
+	// A VLAN is attached on its link's .network
+	vlansOf := make(map[string][]string)
+	for _, name := range sortedKeys(config.Network.VLANs) {
+		vlan := config.Network.VLANs[name]
+		vlansOf[vlan.Link] = append(vlansOf[vlan.Link], name)
+		netdev := &iniFile{}
+		netdev.Section("NetDev")
+		netdev.Set("Name", name)
+		netdev.Set("Kind", "vlan")
+		netdev.Section("VLAN")
+		netdev.Set("Id", fmt.Sprint(vlan.ID))
+		files["10-netplan-"+name+".netdev"] = netdev.String()
+	}
+	for _, name := range sortedKeys(config.Network.DummyDevices) {
+		files["10-netplan-"+name+".netdev"] = networkdNetdev(name, "dummy")
+	}
+
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		if _, isModem := config.Network.Modems[name]; isModem {
+			return
+		}
+		network, skipped := networkdNetwork(name, *s, memberOf[name], vlansOf[name])
+		files["10-netplan-"+name+".network"] = network
+		for _, setting := range skipped {
+			warnings = append(warnings, fmt.Sprintf("%s: %s is not exported to networkd", name, setting))
+		}
+	})
+
+	for _, name := range sortedKeys(config.Network.Modems) {
+		warnings = append(warnings, fmt.Sprintf("%s: modems are managed by ModemManager and have no networkd equivalent", name))
+	}
+	for _, name := range sortedKeys(config.Network.NMDevices) {
+		warnings = append(warnings, fmt.Sprintf("%s: nm-devices are NetworkManager only and have no networkd equivalent", name))
+	}
+	return files, warnings
+}
+
+func networkdNetdev(name, kind string) string {
+	netdev := &iniFile{}
+	netdev.Section("NetDev")
+	netdev.Set("Name", name)
+	netdev.Set("Kind", kind)
+	return netdev.String()
+}
+
+// networkdNetwork returns the .network file for one interface, and the
+// settings it had to leave out. member is the kind ("Bond" or "Bridge")
+// and name of the interface's parent, if it has one, and vlans are the
+// VLANs using the interface as their link.
+func networkdNetwork(name string, s interfaceSettings, member [2]string, vlans []string) (string, []string) {
+	var skipped []string
+	network := &iniFile{}
+
+	network.Section("Match")
+	network.Set("Name", name)
+
+	network.Section("Link")
+	if s.MACAddress != "" {
+		if macAddressKeywords[s.MACAddress] {
+			skipped = append(skipped, "macaddress "+s.MACAddress)
+		} else {
+			network.Set("MACAddress", s.MACAddress)
+		}
+	}
+	if s.MTU != 0 {
+		network.Set("MTUBytes", fmt.Sprint(s.MTU))
+	}
+	if s.Optional {
+		network.Set("RequiredForOnline", "no")
+	}
+	if s.Promiscuous {
+		network.Set("Promiscuous", "yes")
+	}
+
+	network.Section("Network")
+	dhcp4 := s.DHCP4 != nil && *s.DHCP4
+	dhcp6 := s.DHCP6 != nil && *s.DHCP6
+	switch {
+	case dhcp4 && dhcp6:
+		network.Set("DHCP", "yes")
+	case dhcp4:
+		network.Set("DHCP", "ipv4")
+	case dhcp6:
+		network.Set("DHCP", "ipv6")
+	}
+	if s.LinkLocal != nil {
+		switch strings.Join(s.LinkLocal, ",") {
+		case "":
+			network.Set("LinkLocalAddressing", "no")
+		case "ipv4":
+			network.Set("LinkLocalAddressing", "ipv4")
+		case "ipv6":
+			network.Set("LinkLocalAddressing", "ipv6")
+		default:
+			network.Set("LinkLocalAddressing", "yes")
+		}
+	}
+	if s.AcceptRA != nil {
+		network.Set("IPv6AcceptRA", fmt.Sprint(*s.AcceptRA))
+	}
+	if s.Critical {
+		network.Set("KeepConfiguration", "yes")
+	}
+	if member[0] != "" {
+		network.Set(member[0], member[1])
+	}
+	for _, vlan := range vlans {
+		network.Set("VLAN", vlan)
+	}
+	for _, addr := range s.Addresses {
+		network.Set("Address", addr)
+	}
+	if s.Gateway4 != "" {
+		network.Set("Gateway", s.Gateway4)
+	}
+	if s.Gateway6 != "" {
+		network.Set("Gateway", s.Gateway6)
+	}
+	if s.Nameservers != nil {
+		for _, ns := range s.Nameservers.Addresses {
+			network.Set("DNS", ns)
+		}
+		if len(s.Nameservers.Search) > 0 {
+			network.Set("Domains", strings.Join(s.Nameservers.Search, " "))
+		}
+	}
+
+	for _, route := range s.Routes {
+		network.Section("Route")
+		if route.To != "default" {
+			network.Set("Destination", route.To)
+		}
+		if route.Via != "" {
+			network.Set("Gateway", route.Via)
+		}
+		if route.OnLink {
+			network.Set("GatewayOnLink", "yes")
+		}
+		if route.Metric != 0 {
+			network.Set("Metric", fmt.Sprint(route.Metric))
+		}
+		if route.Table != 0 {
+			network.Set("Table", fmt.Sprint(route.Table))
+		}
+		if route.MTU != 0 {
+			network.Set("MTUBytes", fmt.Sprint(route.MTU))
+		}
+	}
+
+	if len(s.DHCP4Overrides) > 0 {
+		skipped = append(skipped, "dhcp4-overrides")
+	}
+	if len(s.DHCP6Overrides) > 0 {
+		skipped = append(skipped, "dhcp6-overrides")
+	}
+	return network.String(), skipped
+}
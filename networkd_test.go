@@ -0,0 +1,154 @@
+/*
+Tests for the systemd-networkd export
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportNetworkdEthernet(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{
+				Type:        "ethernet",
+				Name:        "eth0",
+				MTU:         "9000",
+				UseStatic:   true,
+				Addresses:   "192.168.1.10/24",
+				Gateway4:    "192.168.1.1",
+				Routes:      "10.0.0.0/8 192.168.1.254 100",
+				Nameservers: "1.1.1.1",
+			},
+			{Type: "ethernet", Name: "eth1"},
+		},
+		Renderer: "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	files, warnings := exportNetworkd(config)
+	if len(files) != 2 || len(warnings) != 0 {
+		t.Fatalf("Expected two files and no warnings, got %v and %v", files, warnings)
+	}
+
+	want := `[Match]
+Name=eth0
+
+[Link]
+MTUBytes=9000
+
+[Network]
+Address=192.168.1.10/24
+Gateway=192.168.1.1
+DNS=1.1.1.1
+
+[Route]
+Destination=10.0.0.0/8
+Gateway=192.168.1.254
+Metric=100
+`
+	if got := files["10-netplan-eth0.network"]; got != want {
+		t.Errorf("eth0 network file:\n%s\nwant:\n%s", got, want)
+	}
+	if got := files["10-netplan-eth1.network"]; !strings.Contains(got, "[Network]\nDHCP=ipv4\n") {
+		t.Errorf("Expected DHCP on eth1, got:\n%s", got)
+	}
+}
+
+func TestExportNetworkdBond(t *testing.T) {
+	body := `{"interfaces":[{"type":"bond","name":"bond0","bondInterfaces":"eth0,eth1","bondMode":"802.3ad","bondMiiMonitorInterval":"100"}],"renderer":"networkd"}`
+	rec := httptest.NewRecorder()
+	handleExportNetworkd(rec, httptest.NewRequest(http.MethodPost, "/api/v1/export/networkd", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Files map[string]string `json:"files"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[NetDev]\nName=bond0\nKind=bond\n\n[Bond]\nMode=802.3ad\nMIIMonitorSec=100ms\n"
+	if got := response.Files["10-netplan-bond0.netdev"]; got != want {
+		t.Errorf("bond0 netdev file:\n%s\nwant:\n%s", got, want)
+	}
+	for _, member := range []string{"eth0", "eth1"} {
+		if got := response.Files["10-netplan-"+member+".network"]; !strings.Contains(got, "Bond=bond0\n") || strings.Contains(got, "DHCP=") {
+			t.Errorf("Expected %s to join bond0 without DHCP, got:\n%s", member, got)
+		}
+	}
+	if _, ok := response.Files["10-netplan-bond0.network"]; !ok {
+		t.Error("Expected a .network file for bond0")
+	}
+}
+
+func TestExportNetworkdOptional(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", Optional: true},
+			{Type: "ethernet", Name: "eth1"},
+		},
+		Renderer: "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	files, _ := exportNetworkd(config)
+	if got := files["10-netplan-eth0.network"]; !strings.Contains(got, "[Link]\nRequiredForOnline=no\n") {
+		t.Errorf("Expected the optional eth0 to have RequiredForOnline=no, got:\n%s", got)
+	}
+	if got := files["10-netplan-eth1.network"]; strings.Contains(got, "RequiredForOnline") {
+		t.Errorf("Expected eth1 to keep networkd's default RequiredForOnline, got:\n%s", got)
+	}
+}
+
+func TestExportNetworkdPromiscuous(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0", Promiscuous: true}},
+		Renderer:   "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	// netplan has no key for it, so the YAML can't carry it for networkd
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "eth0: netplan can't set promiscuous mode for networkd") {
+		t.Errorf("Expected a promiscuous mode warning for eth0, got %v", config.Warnings)
+	}
+	if yaml := configToYAML(config); strings.Contains(yaml, "promiscuous") || strings.Contains(yaml, "networkmanager") {
+		t.Errorf("Expected no promiscuous setting in the networkd YAML, got:\n%s", yaml)
+	}
+	files, _ := exportNetworkd(config)
+	if got := files["10-netplan-eth0.network"]; !strings.Contains(got, "[Link]\nPromiscuous=yes\n") {
+		t.Errorf("Expected eth0 to have Promiscuous=yes, got:\n%s", got)
+	}
+
+	// NetworkManager gets it through passthrough
+	formData.Renderer = "NetworkManager"
+	config, err = generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	expected := "      networkmanager:\n        passthrough:\n          ethernet.accept-all-mac-addresses: \"1\"\n"
+	if yaml := configToYAML(config); !strings.Contains(yaml, expected) || len(config.Warnings) != 0 {
+		t.Errorf("Expected the passthrough setting and no warnings, got %v:\n%s", config.Warnings, yaml)
+	}
+}
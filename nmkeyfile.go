@@ -0,0 +1,287 @@
+/*
+Export of generated configs as NetworkManager keyfiles
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// handleExportNMKeyfile generates the config for the posted FormData and
+// returns equivalent NetworkManager keyfiles, keyed by file name
+func handleExportNMKeyfile(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	files, warnings := exportNMKeyfiles(config)
+	response := map[string]interface{}{"files": files}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// exportNMKeyfiles translates the config into one .nmconnection keyfile per
+// interface, named and identified like the connections netplan itself
+// generates. The keyfiles have no uuid; NetworkManager derives one from the
+// file name. Settings keyfiles can't express, and networkd-only settings
+// stripped from the config, are reported as warnings.
+func exportNMKeyfiles(config *NetplanConfig) (map[string]string, []string) {
+	files := make(map[string]string)
+	warnings := stripRendererSpecific(config, "NetworkManager")
+
+	// Bond and bridge ports name their controller in the connection section
+	portOf := make(map[string][2]string)
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		for _, member := range config.Network.Bonds[name].Interfaces {
+			portOf[member] = [2]string{"bond", name}
+		}
+	}
+	for _, name := range sortedKeys(config.Network.Bridges) {
+		for _, member := range config.Network.Bridges[name].Interfaces {
+			portOf[member] = [2]string{"bridge", name}
+		}
+	}
+
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		keyfile := &iniFile{}
+		keyfile.Section("connection")
+		keyfile.Set("id", "netplan-"+name)
+
+		var connectionType string
+		switch {
+		case hasKey(config.Network.Ethernets, name):
+			connectionType = "ethernet"
+		case hasKey(config.Network.Bonds, name):
+			connectionType = "bond"
+		case hasKey(config.Network.Bridges, name):
+			connectionType = "bridge"
+		case hasKey(config.Network.VLANs, name):
+			connectionType = "vlan"
+		case hasKey(config.Network.DummyDevices, name):
+			connectionType = "dummy"
+		case hasKey(config.Network.Modems, name):
+			connectionType = "gsm"
+		}
+		keyfile.Set("type", connectionType)
+		keyfile.Set("interface-name", name)
+		port, isPort := portOf[name]
+		if isPort {
+			keyfile.Set("controller", port[1])
+			keyfile.Set("port-type", port[0])
+		}
+
+		if connectionType != "gsm" {
+			keyfile.Section("ethernet")
+			if s.MACAddress != "" {
+				keyfile.Set("cloned-mac-address", s.MACAddress)
+			}
+			if s.MTU != 0 {
+				keyfile.Set("mtu", fmt.Sprint(s.MTU))
+			}
+			if s.Promiscuous {
+				keyfile.Set("accept-all-mac-addresses", "1")
+			}
+		}
+
+		switch connectionType {
+		case "bond":
+			parameters := config.Network.Bonds[name].Parameters
+			keyfile.Section("bond")
+			keyfile.Set("mode", parameters.Mode)
+			if parameters.MIIMonitorInterval != 0 {
+				keyfile.Set("miimon", fmt.Sprint(parameters.MIIMonitorInterval))
+			}
+			if parameters.UpDelay != 0 {
+				keyfile.Set("updelay", fmt.Sprint(parameters.UpDelay))
+			}
+			if parameters.DownDelay != 0 {
+				keyfile.Set("downdelay", fmt.Sprint(parameters.DownDelay))
+			}
+			if parameters.MinLinks != 0 {
+				keyfile.Set("min_links", fmt.Sprint(parameters.MinLinks))
+			}
+			if parameters.AllMembersActive != nil && *parameters.AllMembersActive {
+				keyfile.Set("all_slaves_active", "1")
+			}
+		case "vlan":
+			vlan := config.Network.VLANs[name]
+			keyfile.Section("vlan")
+			keyfile.Set("id", fmt.Sprint(vlan.ID))
+			keyfile.Set("parent", vlan.Link)
+		case "gsm":
+			modem := config.Network.Modems[name]
+			keyfile.Section("gsm")
+			if modem.APN != "" {
+				keyfile.Set("apn", modem.APN)
+			}
+			if modem.AutoConfig {
+				keyfile.Set("auto-config", "true")
+			}
+			if modem.Number != "" {
+				keyfile.Set("number", modem.Number)
+			}
+			if modem.PIN != "" {
+				keyfile.Set("pin", modem.PIN)
+			}
+			if s.MTU != 0 {
+				keyfile.Set("mtu", fmt.Sprint(s.MTU))
+			}
+		}
+
+		// Ports have no IP configuration of their own
+		if !isPort {
+			nmKeyfileIP(keyfile, *s)
+		}
+
+		if len(s.DHCP4Overrides) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: dhcp4-overrides are not exported to NetworkManager", name))
+		}
+		if len(s.DHCP6Overrides) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: dhcp6-overrides are not exported to NetworkManager", name))
+		}
+		files["netplan-"+name+".nmconnection"] = keyfile.String()
+	})
+
+	// nm-devices already are NetworkManager settings, so their passthrough
+	// keys become the keyfile as they are
+	for _, name := range sortedKeys(config.Network.NMDevices) {
+		passthrough := config.Network.NMDevices[name].NetworkManager.Passthrough
+		keyfile := &iniFile{}
+		keyfile.SetIn("connection", "id", "netplan-"+name)
+		for _, key := range sortedKeys(passthrough) {
+			section, option, _ := strings.Cut(key, ".")
+			keyfile.SetIn(section, option, passthrough[key])
+		}
+		files["netplan-"+name+".nmconnection"] = keyfile.String()
+	}
+	return files, warnings
+}
+
+// nmKeyfileIP adds the [ipv4] and [ipv6] sections for an interface
+func nmKeyfileIP(keyfile *iniFile, s interfaceSettings) {
+	var addresses4, addresses6, dns4, dns6 []string
+	for _, addr := range s.Addresses {
+		if ip, _, err := net.ParseCIDR(addr); err == nil && ip.To4() == nil {
+			addresses6 = append(addresses6, addr)
+		} else {
+			addresses4 = append(addresses4, addr)
+		}
+	}
+	if s.Nameservers != nil {
+		for _, ns := range s.Nameservers.Addresses {
+			if ip := net.ParseIP(ns); ip != nil && ip.To4() == nil {
+				dns6 = append(dns6, ns)
+			} else {
+				dns4 = append(dns4, ns)
+			}
+		}
+	}
+	var routes4, routes6 []Route
+	for _, route := range s.Routes {
+		if strings.Contains(route.To, ":") || strings.Contains(route.Via, ":") {
+			routes6 = append(routes6, route)
+		} else {
+			routes4 = append(routes4, route)
+		}
+	}
+
+	keyfile.Section("ipv4")
+	switch {
+	case s.DHCP4 != nil && *s.DHCP4:
+		keyfile.Set("method", "auto")
+	case len(addresses4) > 0:
+		keyfile.Set("method", "manual")
+	default:
+		keyfile.Set("method", "disabled")
+	}
+	nmKeyfileAddressing(keyfile, addresses4, s.Gateway4, dns4, routes4)
+	if s.Nameservers != nil && len(s.Nameservers.Search) > 0 {
+		keyfile.Set("dns-search", strings.Join(s.Nameservers.Search, ";")+";")
+	}
+
+	keyfile.Section("ipv6")
+	switch {
+	case s.DHCP6 != nil && *s.DHCP6:
+		keyfile.Set("method", "auto")
+	case len(addresses6) > 0:
+		keyfile.Set("method", "manual")
+	case s.AcceptRA != nil && *s.AcceptRA:
+		keyfile.Set("method", "auto")
+	default:
+		keyfile.Set("method", "ignore")
+	}
+	nmKeyfileAddressing(keyfile, addresses6, s.Gateway6, dns6, routes6)
+}
+
+// nmKeyfileAddressing adds the numbered address and route keys, the
+// gateway and the dns list of one address family
+func nmKeyfileAddressing(keyfile *iniFile, addresses []string, gateway string, dns []string, routes []Route) {
+	for i, addr := range addresses {
+		keyfile.Set(fmt.Sprintf("address%d", i+1), addr)
+	}
+	if gateway != "" {
+		keyfile.Set("gateway", gateway)
+	}
+	if len(dns) > 0 {
+		keyfile.Set("dns", strings.Join(dns, ";")+";")
+	}
+	for i, route := range routes {
+		to := route.To
+		if to == "default" {
+			to = "0.0.0.0/0"
+			if strings.Contains(route.Via, ":") {
+				to = "::/0"
+			}
+		}
+		value := to
+		if route.Via != "" {
+			value += "," + route.Via
+			if route.Metric != 0 {
+				value += fmt.Sprintf(",%d", route.Metric)
+			}
+		} else if route.Metric != 0 {
+			value += fmt.Sprintf(",,%d", route.Metric)
+		}
+		keyfile.Set(fmt.Sprintf("route%d", i+1), value)
+
+		var options []string
+		if route.OnLink {
+			options = append(options, "onlink=true")
+		}
+		if route.Table != 0 {
+			options = append(options, fmt.Sprintf("table=%d", route.Table))
+		}
+		if route.MTU != 0 {
+			options = append(options, fmt.Sprintf("mtu=%d", route.MTU))
+		}
+		if len(options) > 0 {
+			keyfile.Set(fmt.Sprintf("route%d_options", i+1), strings.Join(options, ","))
+		}
+	}
+}
+
+// hasKey reports whether the map has an entry for key
+func hasKey[V any](m map[string]V, key string) bool {
+	_, ok := m[key]
+	return ok
+}
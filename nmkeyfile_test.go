@@ -0,0 +1,116 @@
+/*
+Tests for the NetworkManager keyfile export
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportNMKeyfileEthernet(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{{
+			Type:        "ethernet",
+			Name:        "eth0",
+			MTU:         "1500",
+			UseStatic:   true,
+			Addresses:   "192.168.1.10/24, 2001:db8::10/64",
+			Gateway4:    "192.168.1.1",
+			Routes:      "10.0.0.0/8 192.168.1.254 100 on-link",
+			Nameservers: "1.1.1.1, 8.8.8.8, 2606:4700:4700::1111",
+		}},
+		Renderer: "NetworkManager",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	files, warnings := exportNMKeyfiles(config)
+	if len(files) != 1 || len(warnings) != 0 {
+		t.Fatalf("Expected one file and no warnings, got %v and %v", files, warnings)
+	}
+
+	want := `[connection]
+id=netplan-eth0
+type=ethernet
+interface-name=eth0
+
+[ethernet]
+mtu=1500
+
+[ipv4]
+method=manual
+address1=192.168.1.10/24
+gateway=192.168.1.1
+dns=1.1.1.1;8.8.8.8;
+route1=10.0.0.0/8,192.168.1.254,100
+route1_options=onlink=true
+
+[ipv6]
+method=manual
+address1=2001:db8::10/64
+dns=2606:4700:4700::1111;
+`
+	if got := files["netplan-eth0.nmconnection"]; got != want {
+		t.Errorf("eth0 keyfile:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExportNMKeyfileBond(t *testing.T) {
+	body := `{"interfaces":[{"type":"bond","name":"bond0","bondInterfaces":"eth0,eth1","bondMode":"active-backup","bondMiiMonitorInterval":"100"}],"renderer":"NetworkManager"}`
+	rec := httptest.NewRecorder()
+	handleExportNMKeyfile(rec, httptest.NewRequest(http.MethodPost, "/api/v1/export/nmkeyfile", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Files map[string]string `json:"files"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	bond := response.Files["netplan-bond0.nmconnection"]
+	for _, want := range []string{"type=bond\n", "[bond]\nmode=active-backup\nmiimon=100\n", "[ipv4]\nmethod=auto\n"} {
+		if !strings.Contains(bond, want) {
+			t.Errorf("Expected %q in bond0 keyfile:\n%s", want, bond)
+		}
+	}
+	for _, member := range []string{"eth0", "eth1"} {
+		keyfile := response.Files["netplan-"+member+".nmconnection"]
+		if !strings.Contains(keyfile, "controller=bond0\nport-type=bond\n") || strings.Contains(keyfile, "[ipv4]") {
+			t.Errorf("Expected %s to be a bond0 port without IP settings, got:\n%s", member, keyfile)
+		}
+	}
+}
+
+func TestExportNMKeyfileStripsCritical(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0", Critical: true}},
+		Renderer:   "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	_, warnings := exportNMKeyfiles(config)
+	if len(warnings) != 1 || warnings[0] != "eth0: critical is networkd only and was stripped for NetworkManager" {
+		t.Errorf("Expected a warning that critical was stripped, got %v", warnings)
+	}
+	if config.Network.Ethernets["eth0"].Critical {
+		t.Error("Expected critical to be stripped from the config")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Custom output templates for generated configs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// outputTemplate is the parsed OUTPUT_TEMPLATE, or nil to use the built-in
+// writer
+var outputTemplate *template.Template
+
+// outputTemplateFuncs are available in output templates in addition to the
+// text/template builtins; yaml gives the built-in output, so a template can
+// wrap it instead of laying out every key itself
+var outputTemplateFuncs = template.FuncMap{
+	"yaml": configToYAML,
+}
+
+// loadOutputTemplate parses a text/template file that renders a
+// *NetplanConfig, and tries it on a sample config so that references to
+// missing fields are caught at startup rather than on the first request
+func loadOutputTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(outputTemplateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dhcp4 := true
+	sample := &NetplanConfig{
+		Network: NetworkConfig{
+			Version:   2,
+			Renderer:  "networkd",
+			Ethernets: map[string]EthernetConfig{"eth0": {DHCP4: &dhcp4}},
+		},
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// outputYAML writes config with the output template if there is one,
+// falling back to the built-in writer if the template fails
+func outputYAML(config *NetplanConfig) string {
+	if outputTemplate != nil {
+		var sb strings.Builder
+		err := outputTemplate.Execute(&sb, config)
+		if err == nil {
+			return sb.String()
+		}
+		log.Printf("Output template failed, using the built-in output: %v", err)
+	}
+	return configToYAML(config)
+}
@@ -0,0 +1,56 @@
+/*
+Tests for custom output templates
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "netplan.tmpl")
+	if err := os.WriteFile(path, []byte("# Managed by the network team ({{.Network.Renderer}})\n{{yaml .}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadOutputTemplate(path)
+	if err != nil {
+		t.Fatalf("loadOutputTemplate failed: %v", err)
+	}
+	defer func() { outputTemplate = nil }()
+	outputTemplate = tmpl
+
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+		Renderer:   "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := renderYAML(config, formData)
+	expected := "# Managed by the network team (networkd)\n" + configToYAML(config)
+	if yaml != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, yaml)
+	}
+
+	// Templates referring to fields that don't exist are rejected up front
+	if err := os.WriteFile(path, []byte("{{.Network.Hostname}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadOutputTemplate(path); err == nil || !strings.Contains(err.Error(), "Hostname") {
+		t.Errorf("Expected an error for the missing field, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Named interface profiles requests can start from
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// profiles are the interface sets loaded from PROFILES_FILE, keyed by
+// profile name such as "web-server"
+var profiles map[string][]InterfaceDefinition
+
+// loadProfiles reads a JSON object mapping profile names to lists of
+// interfaces, in the same form as the interfaces of a request
+func loadProfiles(path string) (map[string][]InterfaceDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var loaded map[string][]InterfaceDefinition
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, err
+	}
+	for name, interfaces := range loaded {
+		if len(interfaces) == 0 {
+			return nil, fmt.Errorf("profile %s has no interfaces", name)
+		}
+		for _, iface := range interfaces {
+			if iface.Name == "" {
+				return nil, fmt.Errorf("profile %s has an interface without a name", name)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// applyProfile seeds formData's interfaces from its profile. An interface
+// of the request with the same name as one of the profile's overrides the
+// fields it sets; other interfaces of the request are added after the
+// profile's.
+func applyProfile(formData FormData) (FormData, error) {
+	if formData.Profile == "" {
+		return formData, nil
+	}
+	profile, ok := profiles[formData.Profile]
+	if !ok {
+		return formData, fmt.Errorf("unknown profile %q", formData.Profile)
+	}
+
+	interfaces := append([]InterfaceDefinition(nil), profile...)
+	for _, iface := range formData.Interfaces {
+		i := indexOfInterface(interfaces, iface.Name)
+		if i < 0 {
+			interfaces = append(interfaces, iface)
+			continue
+		}
+		overrideFields(reflect.ValueOf(&interfaces[i]).Elem(), reflect.ValueOf(iface))
+	}
+	formData.Interfaces = interfaces
+	return formData, nil
+}
+
+func indexOfInterface(interfaces []InterfaceDefinition, name string) int {
+	for i, iface := range interfaces {
+		if iface.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// overrideFields copies the fields of override that aren't their zero value
+// into the struct dst. A profile's true booleans therefore can't be turned
+// off by a request, only its unset ones turned on.
+func overrideFields(dst, override reflect.Value) {
+	for i := 0; i < override.NumField(); i++ {
+		if field := override.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+}
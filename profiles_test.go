@@ -0,0 +1,57 @@
+/*
+Tests for named interface profiles
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `{"web-server":[` +
+		`{"type":"ethernet","name":"eth0","useStatic":true,"addresses":"10.0.0.10/24","gateway4":"10.0.0.1","nameservers":"10.0.0.2"},` +
+		`{"type":"ethernet","name":"eth1"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadProfiles(path)
+	if err != nil {
+		t.Fatalf("loadProfiles failed: %v", err)
+	}
+	defer func() { profiles = nil }()
+	profiles = loaded
+
+	config, err := generateNetplanConfig(FormData{
+		Profile:    "web-server",
+		Interfaces: []InterfaceDefinition{{Name: "eth0", Addresses: "10.0.0.11/24"}},
+		Renderer:   "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	yaml := configToYAML(config)
+	for _, expected := range []string{"- 10.0.0.11/24", "gateway4: 10.0.0.1", "- 10.0.0.2", "eth1:"} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, yaml)
+		}
+	}
+	if strings.Contains(yaml, "10.0.0.10/24") {
+		t.Errorf("Expected the profile's address to be overridden:\n%s", yaml)
+	}
+
+	if _, err := generateNetplanConfig(FormData{Profile: "db-server"}); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("Expected an unknown profile error, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Settings only one renderer supports
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+)
+
+// stripRendererSpecific removes the settings the other renderer supports
+// but renderer doesn't from config, so an export for renderer only has
+// settings it can express, and returns a warning for each one removed:
+// critical is networkd only, and the NetworkManager passthrough of
+// ethernets is NetworkManager only.
+func stripRendererSpecific(config *NetplanConfig, renderer string) []string {
+	var stripped []string
+	switch renderer {
+	case "NetworkManager":
+		config.forEachInterface(func(name string, s *interfaceSettings) {
+			if s.Critical {
+				s.Critical = false
+				stripped = append(stripped, fmt.Sprintf("%s: critical is networkd only and was stripped for NetworkManager", name))
+			}
+		})
+	case "networkd":
+		for _, name := range sortedKeys(config.Network.Ethernets) {
+			eth := config.Network.Ethernets[name]
+			if eth.NetworkManager != nil {
+				eth.NetworkManager = nil
+				config.Network.Ethernets[name] = eth
+				stripped = append(stripped, fmt.Sprintf("%s: networkmanager passthrough is NetworkManager only and was stripped for networkd", name))
+			}
+		}
+	}
+	return stripped
+}
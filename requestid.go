@@ -0,0 +1,53 @@
+/*
+Request IDs for correlating client reports with server logs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// withRequestID gives every request a new ID, sent back in the
+// X-Request-ID header and available to handlers through requestID
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := newUUID()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID withRequestID gave the request, or "" if it has
+// none
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logValidationError logs a request rejected as invalid, with its ID
+func logValidationError(r *http.Request, err error) {
+	slog.Warn("validation failed", "request_id", requestID(r.Context()), "method", r.Method, "path", r.URL.Path, "error", err.Error())
+}
+
+// writeValidationError logs err and responds with it as a JSON error
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	logValidationError(r, err)
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,59 @@
+/*
+Tests for request IDs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	var handlerID string
+	handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerID = requestID(r.Context())
+		handleExplain(w, r)
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v1/explain", strings.NewReader(`{"interfaces":[]}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	id := w.Header().Get("X-Request-ID")
+	if !savedIDPattern.MatchString(id) {
+		t.Fatalf("Expected a UUID in X-Request-ID, got %q", id)
+	}
+	if handlerID != id {
+		t.Errorf("Handler saw request ID %q, header has %q", handlerID, id)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if !strings.Contains(logs.String(), "request_id="+id) || !strings.Contains(logs.String(), "at least one interface is required") {
+		t.Errorf("Expected the validation failure logged with the request ID, got %q", logs.String())
+	}
+
+	// Every request gets its own ID
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if other := w.Header().Get("X-Request-ID"); other == "" || other == id {
+		t.Errorf("Expected a new request ID, got %q", other)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Server-side persistence of generated configs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// savedConfigs stores every successful generation when SAVE_DIR is set
+var savedConfigs *configStore
+
+// timeNow and randReader are the sources of timestamps and random IDs;
+// tests replace them to make saved configs deterministic
+var (
+	timeNow              = time.Now
+	randReader io.Reader = rand.Reader
+)
+
+// savedIDPattern matches the random (version 4) UUIDs used as saved
+// config IDs; anything else is rejected before touching the filesystem
+var savedIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// savedMetadata is stored next to each saved config as <id>.json
+type savedMetadata struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"created"`
+	Renderer   string    `json:"renderer"`
+	Interfaces []string  `json:"interfaces"`
+	Warnings   []string  `json:"warnings,omitempty"`
+}
+
+// configStore keeps generated configs as <id>.yaml files in a directory
+type configStore struct {
+	dir string
+}
+
+func newConfigStore(dir string) *configStore {
+	return &configStore{dir: dir}
+}
+
+// Save writes the YAML and its metadata under a new ID and returns the ID
+func (s *configStore) Save(yaml string, config *NetplanConfig) (string, error) {
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	metadata := savedMetadata{
+		ID:       id,
+		Created:  timeNow().UTC(),
+		Renderer: config.Network.Renderer,
+		Warnings: config.Warnings,
+	}
+	config.forEachInterface(func(name string, s *interfaceSettings) {
+		metadata.Interfaces = append(metadata.Interfaces, name)
+	})
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(s.dir, id+".yaml"), []byte(yaml), 0o640); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, id+".json"), data, 0o640); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Load returns a saved config's YAML; the error satisfies os.IsNotExist
+// for unknown IDs
+func (s *configStore) Load(id string) (string, error) {
+	if !savedIDPattern.MatchString(id) {
+		return "", os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".yaml"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(randReader, b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// handleSaved serves GET /saved/{id} with the saved YAML
+func handleSaved(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	yaml, err := savedConfigs.Load(strings.TrimPrefix(r.URL.Path, "/saved/"))
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to read saved config", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Write([]byte(yaml))
+}
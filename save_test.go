@@ -0,0 +1,106 @@
+/*
+Tests for saved configs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndRetrieve(t *testing.T) {
+	dir := t.TempDir()
+	savedConfigs = newConfigStore(dir)
+	defer func() { savedConfigs = nil }()
+
+	body := `{"interfaces":[{"type":"ethernet","name":"eth0"}],"renderer":"networkd"}`
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handleGenerate(rec, req)
+
+	var response struct {
+		YAML string `json:"yaml"`
+		ID   string `json:"id"`
+		URL  string `json:"url"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if !savedIDPattern.MatchString(response.ID) || response.URL != "/saved/"+response.ID {
+		t.Fatalf("unexpected id %q and url %q", response.ID, response.URL)
+	}
+
+	var metadata savedMetadata
+	data, err := os.ReadFile(filepath.Join(dir, response.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ID != response.ID || metadata.Renderer != "networkd" || len(metadata.Interfaces) != 1 || metadata.Interfaces[0] != "eth0" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	rec = httptest.NewRecorder()
+	handleSaved(rec, httptest.NewRequest(http.MethodGet, response.URL, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != response.YAML {
+		t.Errorf("GET %s = %d %q, want the generated YAML", response.URL, rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/saved/00000000-0000-4000-8000-000000000000", "/saved/..%2Fsecret", "/saved/"} {
+		rec = httptest.NewRecorder()
+		handleSaved(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestSaveDeterministic(t *testing.T) {
+	created := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	defer func(now func() time.Time, reader io.Reader) { timeNow, randReader = now, reader }(timeNow, randReader)
+	timeNow = func() time.Time { return created }
+	randReader = bytes.NewReader(bytes.Repeat([]byte{0xab}, 16))
+
+	dir := t.TempDir()
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+		Renderer:   "networkd",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := newConfigStore(dir).Save(configToYAML(config), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "abababab-abab-4bab-abab-abababababab" {
+		t.Errorf("Save returned id %q, want one derived from the injected random bytes", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"created": "2025-06-01T12:00:00Z"`) {
+		t.Errorf("Expected the injected timestamp in the metadata, got %s", data)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Validation of generated configs against a bundled netplan schema
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+//go:embed schema/netplan.schema.json
+var netplanSchemaJSON []byte
+
+// netplanSchema is parsed once at startup; like the templates it is
+// embedded, so a parse error is a build problem and panics immediately
+var netplanSchema = mustParseSchema(netplanSchemaJSON)
+
+// jsonSchema is the subset of JSON Schema the bundled schema uses: type,
+// enum, minimum and maximum, properties, required, additionalProperties,
+// items, allOf and $ref to one of the schema's $defs. A schema of false
+// accepts nothing.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	AllOf                []*jsonSchema          `json:"allOf"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+
+	never bool
+}
+
+// UnmarshalJSON accepts the boolean schemas true and false besides objects
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true":
+		*s = jsonSchema{}
+		return nil
+	case "false":
+		*s = jsonSchema{never: true}
+		return nil
+	}
+	type plain jsonSchema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+func mustParseSchema(data []byte) *jsonSchema {
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		panic("invalid bundled schema: " + err.Error())
+	}
+	return &schema
+}
+
+// checkSchema validates the config as it is written against the bundled
+// schema, returning the first violation found
+func checkSchema(config *NetplanConfig) error {
+	value := schemaValue(reflect.ValueOf(config.Network))
+	if err := netplanSchema.validate(netplanSchema, map[string]interface{}{"network": value}, ""); err != nil {
+		return fmt.Errorf("schema violation: %v", err)
+	}
+	return nil
+}
+
+// validate checks value, as decoded from JSON, against s; root holds the
+// $defs that references are resolved in, and path locates value in the
+// config for error messages
+func (s *jsonSchema) validate(root *jsonSchema, value interface{}, path string) error {
+	if s.never {
+		return fmt.Errorf("%s is not allowed", path)
+	}
+	if s.Ref != "" {
+		def, ok := root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema reference %s", path, s.Ref)
+		}
+		if err := def.validate(root, value, path); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.AllOf {
+		if err := sub.validate(root, value, path); err != nil {
+			return err
+		}
+	}
+
+	if s.Type != "" && !hasSchemaType(value, s.Type) {
+		return fmt.Errorf("%s: expected %s, got %v", path, s.Type, value)
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		var allowed []string
+		for _, e := range s.Enum {
+			allowed = append(allowed, fmt.Sprint(e))
+		}
+		return fmt.Errorf("%s: %v is not one of %s", path, value, strings.Join(allowed, ", "))
+	}
+	if n, ok := value.(float64); ok {
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Errorf("%s: %v is more than %v", path, n, *s.Maximum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s: %s is required", path, key)
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			sub, ok := s.Properties[key]
+			if !ok {
+				sub = s.AdditionalProperties
+			}
+			if sub == nil {
+				continue
+			}
+			if err := sub.validate(root, v[key], joinSchemaPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(root, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func hasSchemaType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	return false
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaValue converts a config struct to the value JSON would decode its
+// YAML to: structs become maps keyed by their yaml tags, numbers become
+// float64, and fields that are left out of the YAML (yaml:"-" or zero) are
+// left out. A nil pointer is left out, while a pointer to false is kept.
+func schemaValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return schemaValue(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			field := v.Field(i)
+			if name == "" || name == "-" || field.IsZero() {
+				continue
+			}
+			m[name] = schemaValue(field)
+		}
+		return m
+	case reflect.Map:
+		m := make(map[string]interface{})
+		for _, key := range v.MapKeys() {
+			m[key.String()] = schemaValue(v.MapIndex(key))
+		}
+		return m
+	case reflect.Slice:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = schemaValue(v.Index(i))
+		}
+		return list
+	case reflect.Int, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,42 @@
+/*
+Tests for validation against the bundled netplan schema
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0,eth1", BondMode: "802.3ad", UseStatic: true, Addresses: "10.0.0.2/24", Gateway4: "10.0.0.1"},
+			{Type: "vlan", Name: "vlan10", VLANID: "10", VLANLink: "bond0"},
+		},
+		Renderer: "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+	if err := checkSchema(config); err != nil {
+		t.Errorf("Expected the generated config to match the schema, got %v", err)
+	}
+
+	// Generation rejects bad modes itself, so break the config afterwards
+	bond := config.Network.Bonds["bond0"]
+	bond.Parameters.Mode = "fastest"
+	config.Network.Bonds["bond0"] = bond
+	err = checkSchema(config)
+	if err == nil || !strings.Contains(err.Error(), "network.bonds.bond0.parameters.mode: fastest is not one of balance-rr") {
+		t.Errorf("Expected a schema violation for the bond mode, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Developer self-test for the YAML writer
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// selftestExample is a named, representative form submission
+type selftestExample struct {
+	Name     string
+	FormData FormData
+}
+
+// selftestExamples cover each interface type and the optional settings the
+// writer emits
+var selftestExamples = []selftestExample{
+	{
+		Name: "dhcp ethernet",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+			Renderer:   "networkd",
+		},
+	},
+	{
+		Name: "static ethernet",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{{
+				Type:        "ethernet",
+				Name:        "eth0",
+				MACAddress:  "00:11:22:33:44:55",
+				MTU:         "9000",
+				UseStatic:   true,
+				Addresses:   "192.168.1.10/24, 2001:db8::10/64",
+				Gateway4:    "192.168.1.1",
+				Routes:      "10.0.0.0/8 192.168.1.254 100\ndefault 10.255.255.1 on-link",
+				Nameservers: "1.1.1.1, 2606:4700:4700::1111",
+			}},
+			Renderer: "networkd",
+		},
+	},
+	{
+		Name: "ipv6 options",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{{
+				Type:           "ethernet",
+				Name:           "enp3s0",
+				MACAddress:     "stable",
+				Optional:       true,
+				LinkLocal:      "ipv4, ipv6",
+				DHCP4Overrides: "use-dns=false, route-metric=200, hostname=node1",
+			}},
+			Renderer: "NetworkManager",
+		},
+	},
+	{
+		Name: "bond under bridge",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{
+				{
+					Type:           "bond",
+					Name:           "bond0",
+					BondInterfaces: "eth0,eth1",
+					BondMode:       "802.3ad",
+					BondMIIMonitor: "100ms",
+					BondMinLinks:   "1",
+				},
+				{
+					Type:             "bridge",
+					Name:             "br0",
+					BridgeInterfaces: "bond0,eth2",
+					UseStatic:        true,
+					Addresses:        "10.0.0.2/24",
+					Gateway4:         "10.0.0.1",
+					Critical:         true,
+				},
+			},
+			Renderer:      "networkd",
+			TargetRelease: "24.04",
+		},
+	},
+	{
+		Name: "dummy device",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{{Type: "dummy", Name: "dummy0", Addresses: "10.10.10.1/32"}},
+			Renderer:   "networkd",
+		},
+	},
+	{
+		Name: "VLAN on jumbo ethernet",
+		FormData: FormData{
+			Interfaces: []InterfaceDefinition{
+				{Type: "ethernet", Name: "eth0", MTU: "9000"},
+				{Type: "vlan", Name: "vlan100", VLANID: "100", VLANLink: "eth0", MTU: "8996", UseStatic: true, Addresses: "10.100.0.2/24"},
+			},
+			Renderer: "networkd",
+		},
+	},
+}
+
+// runSelftest round-trips every example through generate, parse and
+// generate again, and returns a description of each one whose YAML changed
+// or could not be parsed
+func runSelftest() []string {
+	var failures []string
+	for _, example := range selftestExamples {
+		config, err := generateNetplanConfig(example.FormData)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: generate failed: %v", example.Name, err))
+			continue
+		}
+		yaml := configToYAML(config)
+
+		parsed, err := parseNetplanYAML(yaml)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: parse failed: %v", example.Name, err))
+			continue
+		}
+		if again := configToYAML(parsed); again != yaml {
+			failures = append(failures, fmt.Sprintf("%s: output changed after round trip:\n%s\n---\n%s", example.Name, yaml, again))
+		}
+	}
+	return failures
+}
+
+// handleSelftest reports the results of runSelftest; it is only
+// registered when DEBUG is enabled
+func handleSelftest(w http.ResponseWriter, r *http.Request) {
+	failures := runSelftest()
+	status := http.StatusOK
+	if len(failures) > 0 {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"examples": len(selftestExamples),
+		"failures": failures,
+	})
+}
@@ -0,0 +1,22 @@
+/*
+Tests for the developer self-test
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSelftestExamples(t *testing.T) {
+	for _, failure := range runSelftest() {
+		t.Error(failure)
+	}
+}
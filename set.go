@@ -0,0 +1,164 @@
+/*
+netplan set style partial updates of generated configurations
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// SetRequest is the body of /api/v1/set: the config generated from Base is
+// updated by setting the dotted Path to Value, like `netplan set`
+type SetRequest struct {
+	Base  FormData    `json:"base"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// handleSet applies a single dotted-path update to the generated config
+// and returns the resulting YAML once it passes the checks of a generated
+// config
+func handleSet(w http.ResponseWriter, r *http.Request) {
+	var request SetRequest
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+
+	config, err := generateNetplanConfig(request.Base)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	tree := configToTree(config)
+	if err := setTreePath(tree, request.Path, request.Value); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	yaml := treeToYAML(tree)
+	if err := checkSetResult(yaml); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"yaml": yaml})
+}
+
+// checkSetResult parses the updated YAML back and runs the checks a
+// generated config gets, so an update that netplan would reject, such as
+// an unknown key or a value of the wrong type, is refused
+func checkSetResult(yaml string) error {
+	config, err := parseNetplanYAML(yaml)
+	if err != nil {
+		return fmt.Errorf("invalid update: %v", err)
+	}
+	if err := checkSchema(config); err != nil {
+		return fmt.Errorf("invalid update: %v", err)
+	}
+	if violations := lintYAML(yaml, 2); len(violations) > 0 {
+		return fmt.Errorf("invalid update: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// setTreePath sets the value at a dotted path such as "ethernets.eth0.mtu",
+// relative to the network key; a leading "network." is accepted. Missing
+// mappings along the path are created, and a nil value removes the key.
+func setTreePath(tree *yamlMap, path string, value interface{}) error {
+	path = strings.TrimPrefix(path, "network.")
+	if path == "" || path == "network" {
+		return fmt.Errorf("path is required")
+	}
+
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("invalid path %q: empty segment", path)
+		}
+	}
+
+	node, ok := tree.Get("network")
+	current, isMap := node.(*yamlMap)
+	if !ok || !isMap {
+		return fmt.Errorf("config has no network mapping")
+	}
+
+	for i, segment := range segments[:len(segments)-1] {
+		next, exists := current.Get(segment)
+		if !exists {
+			if value == nil {
+				return nil
+			}
+			child := newYAMLMap()
+			current.Set(segment, child)
+			current = child
+			continue
+		}
+		child, isMap := next.(*yamlMap)
+		if !isMap {
+			return fmt.Errorf("invalid path %q: %s is not a mapping", path, strings.Join(segments[:i+1], "."))
+		}
+		current = child
+	}
+
+	key := segments[len(segments)-1]
+	if value == nil {
+		current.Delete(key)
+		return nil
+	}
+
+	node, err := jsonToTree(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %v", path, err)
+	}
+	current.Set(key, node)
+	return nil
+}
+
+// jsonToTree converts a decoded JSON value into a YAML tree node, turning
+// whole numbers back into ints
+func jsonToTree(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := newYAMLMap()
+		for _, key := range sortedKeys(v) {
+			node, err := jsonToTree(v[key])
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, node)
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			node, err := jsonToTree(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, node)
+		}
+		return list, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v), nil
+		}
+		return v, nil
+	case string, bool:
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("null is not allowed inside a value")
+	}
+	return nil, fmt.Errorf("unsupported value %v", value)
+}
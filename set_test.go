@@ -0,0 +1,130 @@
+/*
+Tests for netplan set style partial updates
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetMTUViaPath(t *testing.T) {
+	body := `{
+		"base": {"interfaces": [{"type": "ethernet", "name": "eth0"}], "renderer": "networkd"},
+		"path": "ethernets.eth0.mtu",
+		"value": 9000
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/set", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handleSet(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+
+	expected := "network:\n  version: 2\n  renderer: networkd\n  ethernets:\n    eth0:\n      dhcp4: true\n      mtu: 9000\n"
+	if response["yaml"] != expected {
+		t.Errorf("Unexpected YAML:\n%s\nwant:\n%s", response["yaml"], expected)
+	}
+}
+
+func TestSetTreePath(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: "eth0"}},
+		Renderer:   "networkd",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	tree := configToTree(config)
+
+	if err := setTreePath(tree, "network.bridges.br0.interfaces", []interface{}{"eth0"}); err != nil {
+		t.Errorf("Expected missing mappings to be created: %v", err)
+	}
+	if err := setTreePath(tree, "ethernets.eth0.dhcp4", nil); err != nil {
+		t.Errorf("Expected null to remove the key: %v", err)
+	}
+	if err := setTreePath(tree, "ethernets.eth0..mtu", 1500); err == nil {
+		t.Errorf("Expected error for an empty path segment")
+	}
+	if err := setTreePath(tree, "renderer.foo", "bar"); err == nil {
+		t.Errorf("Expected error when traversing into a scalar")
+	}
+
+	yaml := treeToYAML(tree)
+	if strings.Contains(yaml, "dhcp4") {
+		t.Errorf("Expected dhcp4 to be removed, got:\n%s", yaml)
+	}
+	if !strings.Contains(yaml, "  bridges:\n    br0:\n      interfaces:\n        - eth0\n") {
+		t.Errorf("Expected created bridge, got:\n%s", yaml)
+	}
+}
+
+func TestSetWithoutRenderer(t *testing.T) {
+	body := `{
+		"base": {"interfaces": [{"type": "ethernet", "name": "eth0"}]},
+		"path": "ethernets.eth0.mtu",
+		"value": 1500
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/set", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handleSet(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if strings.Contains(response["yaml"], "renderer") {
+		t.Errorf("Expected no renderer key without a renderer, got:\n%s", response["yaml"])
+	}
+}
+
+func TestSetRejectsInvalidUpdates(t *testing.T) {
+	tests := []struct {
+		path     string
+		value    string
+		expected string
+	}{
+		{"bogus.thing", `1`, `network: unknown key \"bogus\"`},
+		{"ethernets.eth0.mtu", `"jumbo"`, "mtu"},
+		{"ethernets.eth0.mtu", `20`, "mtu: 20 is less than 68"},
+		{"ethernets.eth0.colour", `"blue"`, `unknown key \"colour\"`},
+	}
+	for _, test := range tests {
+		body := `{
+			"base": {"interfaces": [{"type": "ethernet", "name": "eth0"}], "renderer": "networkd"},
+			"path": "` + test.path + `",
+			"value": ` + test.value + `
+		}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/set", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handleSet(rec, req)
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), test.expected) {
+			t.Errorf("Expected status 400 mentioning %q for %s=%s, got %d: %s", test.expected, test.path, test.value, rec.Code, rec.Body.String())
+		}
+	}
+}
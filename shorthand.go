@@ -0,0 +1,164 @@
+/*
+Conversion of ip-style shorthand into form data
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ShorthandRequest is the body of /api/v1/shorthand
+type ShorthandRequest struct {
+	Shorthand string `json:"shorthand"`
+	Renderer  string `json:"renderer"`
+}
+
+// handleShorthand converts shorthand lines into form data and returns both
+// the form data and the config generated from it
+func handleShorthand(w http.ResponseWriter, r *http.Request) {
+	var request ShorthandRequest
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+
+	formData, err := parseShorthand(request.Shorthand)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	formData.Renderer = request.Renderer
+	writeConvertedFormData(w, r, formData)
+}
+
+// writeConvertedFormData responds with form data converted from another
+// format and the config generated from it, defaulting to networkd
+func writeConvertedFormData(w http.ResponseWriter, r *http.Request, formData FormData) {
+	if formData.Renderer == "" {
+		formData.Renderer = "networkd"
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"formData": formData,
+		"yaml":     renderYAML(config, formData),
+	}
+	if len(config.Warnings) > 0 {
+		response["warnings"] = config.Warnings
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseShorthand parses one ethernet per line in the form
+//
+//	eth0: dhcp
+//	eth1: 192.168.1.10/24 2001:db8::10/64 gw 192.168.1.1 dns 1.1.1.1,8.8.8.8 mtu 9000
+//
+// "dhcp" and "dhcp6" enable DHCP, with "dhcp6" alone making the interface
+// IPv6 only; otherwise the addresses are static, and "gw" may be given once
+// per address family. Blank lines and lines starting with # are skipped.
+func parseShorthand(lines string) (FormData, error) {
+	var formData FormData
+	for i, line := range strings.Split(lines, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		iface, err := parseShorthandLine(line)
+		if err != nil {
+			return formData, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		formData.Interfaces = append(formData.Interfaces, iface)
+	}
+	if len(formData.Interfaces) == 0 {
+		return formData, fmt.Errorf("no interfaces given")
+	}
+	return formData, nil
+}
+
+func parseShorthandLine(line string) (InterfaceDefinition, error) {
+	name, rest, found := strings.Cut(line, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return InterfaceDefinition{}, fmt.Errorf("expected \"<interface>: <settings>\", got %q", line)
+	}
+	iface := InterfaceDefinition{Type: "ethernet", Name: name}
+
+	var addresses, nameservers []string
+	dhcp4 := false
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return iface, fmt.Errorf("%s: no settings given", name)
+	}
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch field {
+		case "dhcp", "dhcp4":
+			dhcp4 = true
+		case "dhcp6":
+			iface.DHCP6 = true
+		case "gw", "dns", "mtu":
+			if i+1 == len(fields) {
+				return iface, fmt.Errorf("%s: %s needs a value", name, field)
+			}
+			i++
+			value := fields[i]
+			switch field {
+			case "gw":
+				ip := net.ParseIP(value)
+				if ip == nil {
+					return iface, fmt.Errorf("%s: invalid gateway %s", name, value)
+				}
+				gateway := &iface.Gateway6
+				if ip.To4() != nil {
+					gateway = &iface.Gateway4
+				}
+				if *gateway != "" {
+					return iface, fmt.Errorf("%s: more than one gateway of the same address family: %s and %s", name, *gateway, value)
+				}
+				*gateway = value
+			case "dns":
+				for _, ns := range parseCommaSeparated(value) {
+					if net.ParseIP(ns) == nil {
+						return iface, fmt.Errorf("%s: invalid nameserver %s", name, ns)
+					}
+					nameservers = append(nameservers, ns)
+				}
+			case "mtu":
+				iface.MTU = value
+			}
+		default:
+			if _, _, err := net.ParseCIDR(field); err != nil {
+				return iface, fmt.Errorf("%s: invalid address %s (expected address/prefix, dhcp, dhcp6, gw, dns or mtu)", name, field)
+			}
+			addresses = append(addresses, field)
+		}
+	}
+
+	if len(addresses) > 0 {
+		if dhcp4 {
+			return iface, fmt.Errorf("%s: dhcp can't be combined with static addresses", name)
+		}
+		iface.UseStatic = true
+		iface.Addresses = strings.Join(addresses, ", ")
+	} else if iface.DHCP6 && !dhcp4 {
+		iface.IPv6Only = true
+	}
+	iface.Nameservers = strings.Join(nameservers, ", ")
+	return iface, nil
+}
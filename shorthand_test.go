@@ -0,0 +1,81 @@
+/*
+Tests for ip-style shorthand conversion
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseShorthand(t *testing.T) {
+	formData, err := parseShorthand("eth0: 192.168.1.10/24 gw 192.168.1.1 dns 1.1.1.1,8.8.8.8\n\n# uplink\neth1: dhcp\n")
+	if err != nil {
+		t.Fatalf("parseShorthand failed: %v", err)
+	}
+	if len(formData.Interfaces) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(formData.Interfaces))
+	}
+
+	static := formData.Interfaces[0]
+	if static.Name != "eth0" || !static.UseStatic || static.Addresses != "192.168.1.10/24" || static.Gateway4 != "192.168.1.1" || static.Nameservers != "1.1.1.1, 8.8.8.8" {
+		t.Errorf("Unexpected static interface %+v", static)
+	}
+	dhcp := formData.Interfaces[1]
+	if dhcp.Name != "eth1" || dhcp.UseStatic || dhcp.Addresses != "" {
+		t.Errorf("Unexpected DHCP interface %+v", dhcp)
+	}
+
+	if _, err := parseShorthand("eth0: 192.168.1.10/24 fd00::10/64 gw 192.168.1.1 gw fd00::1"); err != nil {
+		t.Errorf("Expected one gateway per address family to be accepted: %v", err)
+	}
+
+	for _, input := range []string{
+		"eth0: 192.168.1.300/24",
+		"eth0: 192.168.1.10",
+		"eth0: 192.168.1.10/24 gw",
+		"eth0: 192.168.1.10/24 gw router",
+		"eth0: 192.168.1.10/24 dhcp",
+		"eth0: 192.168.1.10/24 gw 192.168.1.1 gw 192.168.1.254",
+		"eth0",
+		"eth0:",
+		"",
+	} {
+		if _, err := parseShorthand(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestHandleShorthand(t *testing.T) {
+	body := `{"shorthand":"eth0: 10.0.0.2/24 gw 10.0.0.1\neth1: dhcp"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorthand", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleShorthand(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		YAML string `json:"yaml"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"renderer: networkd", "    eth0:\n      dhcp4: false\n      addresses:\n        - 10.0.0.2/24\n      gateway4: 10.0.0.1\n", "    eth1:\n      dhcp4: true\n"} {
+		if !strings.Contains(response.YAML, want) {
+			t.Errorf("Expected %q in:\n%s", want, response.YAML)
+		}
+	}
+}
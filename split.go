@@ -0,0 +1,212 @@
+/*
+Split output with one netplan file per interface
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SplitFile is one file of split output
+type SplitFile struct {
+	Name string `json:"name"`
+	YAML string `json:"yaml"`
+}
+
+// splitFileName names the file of an interface in split output. Netplan
+// merges every file in /etc/netplan, so the files can be read in any order.
+func splitFileName(name string) string {
+	return "90-netplan-web-" + name + ".yaml"
+}
+
+// disabledSplitFileName names the file of a disabled interface. The prefix
+// differs from splitFileName's, so it can't clash with the file of an active
+// interface of the same name, or of one named "disabled-<name>".
+func disabledSplitFileName(name string) string {
+	return "91-netplan-web-disabled-" + name + ".yaml"
+}
+
+// splitConfig writes config as one file per interface, in the order the
+// interfaces are written, rendering each file like the whole config would
+// be. Every file is a complete netplan config with its own version and
+// renderer, but a bond, bridge or VLAN still needs the files of its members
+// or link installed alongside it. Each disabled interface gets a file that
+// holds only its commented-out definition, named by disabledSplitFileName.
+func splitConfig(config *NetplanConfig, formData FormData) []SplitFile {
+	var files []SplitFile
+	newPart := func(network NetworkConfig) *NetplanConfig {
+		return &NetplanConfig{
+			Network: NetworkConfig{
+				Version:          network.Version,
+				Renderer:         network.Renderer,
+				SectionRenderers: network.SectionRenderers,
+			},
+			IndentWidth:   config.IndentWidth,
+			MinimalOutput: config.MinimalOutput,
+			Explicit:      config.Explicit,
+		}
+	}
+	withDescription := func(part *NetplanConfig, descriptions map[string]string, name string) {
+		if description := descriptions[name]; description != "" {
+			part.Descriptions = map[string]string{name: description}
+		}
+	}
+
+	forEachSplit(config.Network, func(name string, set func(n *NetworkConfig)) {
+		part := newPart(config.Network)
+		withDescription(part, config.Descriptions, name)
+		set(&part.Network)
+		files = append(files, SplitFile{Name: splitFileName(name), YAML: renderYAML(part, formData)})
+	})
+	if config.Disabled != nil {
+		forEachSplit(config.Disabled.Network, func(name string, set func(n *NetworkConfig)) {
+			part := newPart(config.Network)
+			part.Disabled = newPart(config.Disabled.Network)
+			withDescription(part.Disabled, config.Disabled.Descriptions, name)
+			set(&part.Disabled.Network)
+			files = append(files, SplitFile{Name: disabledSplitFileName(name), YAML: renderYAML(part, formData)})
+		})
+	}
+	return files
+}
+
+// forEachSplit calls fn with the name of every interface of n, in the
+// order they are written, and a function that sets just that interface in
+// another NetworkConfig
+func forEachSplit(n NetworkConfig, fn func(name string, set func(n *NetworkConfig))) {
+	for _, name := range sortedKeys(n.Ethernets) {
+		fn(name, func(p *NetworkConfig) { p.Ethernets = map[string]EthernetConfig{name: n.Ethernets[name]} })
+	}
+	for _, name := range sortedKeys(n.Bonds) {
+		fn(name, func(p *NetworkConfig) { p.Bonds = map[string]BondConfig{name: n.Bonds[name]} })
+	}
+	for _, name := range sortedKeys(n.Bridges) {
+		fn(name, func(p *NetworkConfig) { p.Bridges = map[string]BridgeConfig{name: n.Bridges[name]} })
+	}
+	for _, name := range sortedKeys(n.VLANs) {
+		fn(name, func(p *NetworkConfig) { p.VLANs = map[string]VLANConfig{name: n.VLANs[name]} })
+	}
+	for _, name := range sortedKeys(n.DummyDevices) {
+		fn(name, func(p *NetworkConfig) { p.DummyDevices = map[string]EthernetConfig{name: n.DummyDevices[name]} })
+	}
+	for _, name := range sortedKeys(n.Modems) {
+		fn(name, func(p *NetworkConfig) { p.Modems = map[string]ModemConfig{name: n.Modems[name]} })
+	}
+	for _, name := range sortedKeys(n.NMDevices) {
+		fn(name, func(p *NetworkConfig) { p.NMDevices = map[string]NMDeviceConfig{name: n.NMDevices[name]} })
+	}
+}
+
+// mergeConfigs combines split files back into one config. The files must
+// agree on the version and renderers, and each interface may only be
+// defined once; netplan would merge such definitions key by key, which
+// split output never relies on.
+func mergeConfigs(parts []*NetplanConfig) (*NetplanConfig, error) {
+	merged := &NetplanConfig{}
+	network := &merged.Network
+	for _, part := range parts {
+		p := part.Network
+		if p.Version != 0 {
+			if network.Version != 0 && network.Version != p.Version {
+				return nil, fmt.Errorf("conflicting versions %d and %d", network.Version, p.Version)
+			}
+			network.Version = p.Version
+		}
+		if p.Renderer != "" {
+			if network.Renderer != "" && network.Renderer != p.Renderer {
+				return nil, fmt.Errorf("conflicting renderers %s and %s", network.Renderer, p.Renderer)
+			}
+			network.Renderer = p.Renderer
+		}
+		for section, renderer := range p.SectionRenderers {
+			if existing, ok := network.SectionRenderers[section]; ok && existing != renderer {
+				return nil, fmt.Errorf("conflicting renderers %s and %s for %s", existing, renderer, section)
+			}
+			if network.SectionRenderers == nil {
+				network.SectionRenderers = make(map[string]string)
+			}
+			network.SectionRenderers[section] = renderer
+		}
+
+		for _, err := range []error{
+			mergeSection(&network.Ethernets, p.Ethernets),
+			mergeSection(&network.Bonds, p.Bonds),
+			mergeSection(&network.Bridges, p.Bridges),
+			mergeSection(&network.VLANs, p.VLANs),
+			mergeSection(&network.DummyDevices, p.DummyDevices),
+			mergeSection(&network.Modems, p.Modems),
+			mergeSection(&network.NMDevices, p.NMDevices),
+		} {
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if network.Version == 0 {
+		network.Version = 2
+	}
+	return merged, nil
+}
+
+// mergeSection adds the interfaces of src to *dst, rejecting any already
+// defined there
+func mergeSection[V any](dst *map[string]V, src map[string]V) error {
+	for name, definition := range src {
+		if hasKey(*dst, name) {
+			return fmt.Errorf("interface %s is defined in more than one file", name)
+		}
+		if *dst == nil {
+			*dst = make(map[string]V)
+		}
+		(*dst)[name] = definition
+	}
+	return nil
+}
+
+// handleSplit generates the posted FormData as one file per interface
+func handleSplit(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"files": splitConfig(config, formData)})
+}
+
+// handleMerge combines posted split files into a single netplan YAML
+func handleMerge(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Files []SplitFile `json:"files"`
+	}
+	if !decodeAPIRequest(w, r, &request) {
+		return
+	}
+	var parts []*NetplanConfig
+	for _, file := range request.Files {
+		part, err := parseNetplanYAML(file.YAML)
+		if err != nil {
+			writeValidationError(w, r, fmt.Errorf("invalid file %s: %v", file.Name, err))
+			return
+		}
+		parts = append(parts, part)
+	}
+	merged, err := mergeConfigs(parts)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"yaml": configToYAML(merged)})
+}
@@ -0,0 +1,118 @@
+/*
+Tests for split output
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitConfig(t *testing.T) {
+	config, err := generateNetplanConfig(FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2"},
+		},
+		Renderer: "NetworkManager",
+	})
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	files := splitConfig(config, FormData{})
+	var names []string
+	var parts []*NetplanConfig
+	for _, file := range files {
+		names = append(names, file.Name)
+		if !strings.HasPrefix(file.YAML, "network:\n  version: 2\n  renderer: NetworkManager\n") {
+			t.Errorf("Expected %s to start with its own version and renderer:\n%s", file.Name, file.YAML)
+		}
+		part, err := parseNetplanYAML(file.YAML)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file.Name, err)
+		}
+		parts = append(parts, part)
+	}
+	expected := "90-netplan-web-eth0.yaml 90-netplan-web-eth1.yaml 90-netplan-web-eth2.yaml 90-netplan-web-bond0.yaml"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Expected files %s, got %s", expected, got)
+	}
+
+	merged, err := mergeConfigs(parts)
+	if err != nil {
+		t.Fatalf("mergeConfigs failed: %v", err)
+	}
+	if got, want := configToYAML(merged), configToYAML(config); got != want {
+		t.Errorf("Expected the merged files to match the config:\n%s\nGot:\n%s", want, got)
+	}
+
+	if _, err := mergeConfigs(append(parts, parts[0])); err == nil || !strings.Contains(err.Error(), "eth0 is defined in more than one file") {
+		t.Errorf("Expected a duplicate interface error, got %v", err)
+	}
+}
+
+func TestSplitConfigRendersLikeWholeConfig(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "ethernet", Name: "eth1", Disabled: true},
+		},
+		Renderer:            "networkd",
+		CommentDisabled:     true,
+		OmitTrailingNewline: true,
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	files := splitConfig(config, formData)
+	if len(files) != 2 {
+		t.Fatalf("Expected a file for eth0 and one for the disabled eth1, got %+v", files)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.YAML, "\n") {
+			t.Errorf("Expected %s without a trailing newline:\n%q", file.Name, file.YAML)
+		}
+	}
+	expected := "network:\n  version: 2\n  renderer: networkd\n# Disabled interfaces:\n#   ethernets:\n#     eth1:\n#       dhcp4: true"
+	if files[1].Name != "91-netplan-web-disabled-eth1.yaml" || files[1].YAML != expected {
+		t.Errorf("Expected the disabled eth1 commented out in its own file:\n%s\nGot %s:\n%s", expected, files[1].Name, files[1].YAML)
+	}
+}
+
+func TestSplitConfigDisabledFileNames(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0"},
+			{Type: "ethernet", Name: "eth0", Disabled: true},
+			{Type: "ethernet", Name: "disabled-eth0"},
+		},
+		Renderer:        "networkd",
+		CommentDisabled: true,
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range splitConfig(config, formData) {
+		if seen[file.Name] {
+			t.Errorf("Expected distinct file names, got %s twice", file.Name)
+		}
+		seen[file.Name] = true
+	}
+	if len(seen) != 3 || !seen["90-netplan-web-eth0.yaml"] || !seen["91-netplan-web-disabled-eth0.yaml"] {
+		t.Errorf("Expected a file for each eth0 and one for disabled-eth0, got %v", seen)
+	}
+}
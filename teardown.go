@@ -0,0 +1,89 @@
+/*
+Teardown configs for rolling back generated configs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"net/http"
+)
+
+// handleTeardown generates the config for the posted FormData and returns
+// the teardown config for it
+func handleTeardown(w http.ResponseWriter, r *http.Request) {
+	var formData FormData
+	if !decodeAPIRequest(w, r, &formData) {
+		return
+	}
+
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	teardown := teardownConfig(config)
+	response := map[string]interface{}{"yaml": renderYAML(teardown, FormData{})}
+	if len(teardown.Warnings) > 0 {
+		response["warnings"] = teardown.Warnings
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// teardownConfig returns a minimal fallback for config: every ethernet,
+// including bond and bridge members, is reset to DHCP, with DHCPv6 where
+// the ethernet used IPv6. Its match and set-name are kept, so the reset
+// still applies to the same NIC under the same name. The virtual devices,
+// modems and nm-devices are left out, which is reported as warnings.
+func teardownConfig(config *NetplanConfig) *NetplanConfig {
+	teardown := &NetplanConfig{
+		Network: NetworkConfig{
+			Version:          2,
+			Renderer:         config.Network.Renderer,
+			Ethernets:        make(map[string]EthernetConfig),
+			SectionRenderers: config.Network.SectionRenderers,
+		},
+		IndentWidth: config.IndentWidth,
+	}
+
+	for _, name := range sortedKeys(config.Network.Ethernets) {
+		eth := config.Network.Ethernets[name]
+		dhcp4 := true
+		reset := EthernetConfig{DHCP4: &dhcp4, Match: eth.Match, SetName: eth.SetName}
+		if (eth.DHCP6 != nil && *eth.DHCP6) || len(eth.Addresses) > len(ipv4Addresses(eth.Addresses)) {
+			dhcp6 := true
+			reset.DHCP6 = &dhcp6
+		}
+		teardown.Network.Ethernets[name] = reset
+	}
+
+	for _, name := range sortedKeys(config.Network.Bonds) {
+		teardown.warn("%s: bond is removed; its members get DHCP instead", name)
+	}
+	for _, name := range sortedKeys(config.Network.Bridges) {
+		teardown.warn("%s: bridge is removed; its members get DHCP instead", name)
+	}
+	for _, name := range sortedKeys(config.Network.VLANs) {
+		teardown.warn("%s: VLAN is removed", name)
+	}
+	for _, name := range sortedKeys(config.Network.DummyDevices) {
+		teardown.warn("%s: dummy device is removed", name)
+	}
+	for _, name := range sortedKeys(config.Network.Modems) {
+		teardown.warn("%s: modem is removed", name)
+	}
+	for _, name := range sortedKeys(config.Network.NMDevices) {
+		teardown.warn("%s: nm-device is removed", name)
+	}
+	if len(teardown.Network.Ethernets) == 0 {
+		teardown.warn("no ethernets to reset; the teardown config has no interfaces")
+	}
+	return teardown
+}
@@ -0,0 +1,84 @@
+/*
+Tests for teardown configs
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTeardownConfig(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1"},
+			{Type: "ethernet", Name: "eth1", UseStatic: true, Addresses: "2001:db8::10/64"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth2,eth3", BondMode: "active-backup", UseStatic: true, Addresses: "10.0.0.2/24"},
+		},
+		Renderer: "networkd",
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	teardown := teardownConfig(config)
+	expected := `network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    eth0:
+      dhcp4: true
+    eth1:
+      dhcp4: true
+      dhcp6: true
+    eth2:
+      dhcp4: true
+    eth3:
+      dhcp4: true
+`
+	if yaml := configToYAML(teardown); yaml != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, yaml)
+	}
+	if len(teardown.Warnings) != 1 || !strings.Contains(teardown.Warnings[0], "bond0: bond is removed") {
+		t.Errorf("Expected a warning about the removed bond, got %v", teardown.Warnings)
+	}
+}
+
+func TestTeardownKeepsMatch(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "nic0", MatchMAC: "00:11:22:33:44:55", SetName: "lan0", UseStatic: true, Addresses: "192.168.1.10/24"},
+		},
+		Renderer:         "networkd",
+		SectionRenderers: map[string]string{"ethernets": "NetworkManager"},
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		t.Fatalf("generateNetplanConfig failed: %v", err)
+	}
+
+	teardown := teardownConfig(config)
+	expected := `network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    renderer: NetworkManager
+    nic0:
+      match:
+        macaddress: "00:11:22:33:44:55"
+      set-name: lan0
+      dhcp4: true
+`
+	if yaml := configToYAML(teardown); yaml != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, yaml)
+	}
+}
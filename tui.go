@@ -0,0 +1,174 @@
+/*
+Interactive generation by prompting on the terminal
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+)
+
+// tuiTypes are the interface types -tui asks about; modems and nm-devices
+// need settings that are better given in a JSON file with -input
+var tuiTypes = []string{"ethernet", "bond", "bridge", "vlan", "dummy"}
+
+// prompter asks questions on out and reads one answer per line from in
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints the question with its default, if any, and returns the
+// answer, or the default for an empty answer. Running out of input is an
+// error, as the questions would otherwise repeat forever.
+func (p *prompter) ask(question, defaultAnswer string) (string, error) {
+	if defaultAnswer != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultAnswer)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	answer := strings.TrimSpace(p.in.Text())
+	if answer == "" {
+		return defaultAnswer, nil
+	}
+	return answer, nil
+}
+
+// choose asks until the answer is one of choices
+func (p *prompter) choose(question string, choices []string, defaultAnswer string) (string, error) {
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, "/")), defaultAnswer)
+		if err != nil {
+			return "", err
+		}
+		if slices.Contains(choices, answer) {
+			return answer, nil
+		}
+		fmt.Fprintf(p.out, "Please answer one of %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// require asks until the answer is not empty
+func (p *prompter) require(question string) (string, error) {
+	for {
+		answer, err := p.ask(question, "")
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		fmt.Fprintln(p.out, "An answer is required")
+	}
+}
+
+// promptFormData asks for the renderer and one interface after another,
+// with the questions for each depending on its type
+func promptFormData(in io.Reader, out io.Writer) (FormData, error) {
+	p := &prompter{in: bufio.NewScanner(in), out: out}
+	var formData FormData
+	var err error
+
+	if formData.Renderer, err = p.choose("Renderer", []string{"networkd", "NetworkManager"}, "networkd"); err != nil {
+		return formData, err
+	}
+	for {
+		iface, err := promptInterface(p)
+		if err != nil {
+			return formData, err
+		}
+		formData.Interfaces = append(formData.Interfaces, iface)
+
+		another, err := p.choose("Add another interface?", []string{"y", "n"}, "n")
+		if err != nil {
+			return formData, err
+		}
+		if another == "n" {
+			return formData, nil
+		}
+	}
+}
+
+// promptInterface asks for the settings of a single interface
+func promptInterface(p *prompter) (InterfaceDefinition, error) {
+	var iface InterfaceDefinition
+	var err error
+	if iface.Type, err = p.choose("Interface type", tuiTypes, "ethernet"); err != nil {
+		return iface, err
+	}
+	if iface.Name, err = p.require("Interface name"); err != nil {
+		return iface, err
+	}
+
+	switch iface.Type {
+	case "bond":
+		iface.BondInterfaces, err = p.require("Member interfaces, comma-separated")
+	case "bridge":
+		iface.BridgeInterfaces, err = p.require("Member interfaces, comma-separated")
+	case "vlan":
+		if iface.VLANID, err = p.require("VLAN ID"); err == nil {
+			iface.VLANLink, err = p.require("Parent interface")
+		}
+	}
+	if err != nil {
+		return iface, err
+	}
+
+	// Dummies have no link to run DHCP on
+	addressing := "static"
+	if iface.Type != "dummy" {
+		if addressing, err = p.choose("Addressing", []string{"dhcp", "static"}, "dhcp"); err != nil {
+			return iface, err
+		}
+	}
+	if addressing == "dhcp" {
+		return iface, nil
+	}
+
+	iface.UseStatic = true
+	if iface.Addresses, err = p.require("Addresses in CIDR notation, comma-separated"); err != nil {
+		return iface, err
+	}
+	if iface.Type == "dummy" {
+		return iface, nil
+	}
+	if iface.Gateway4, err = p.ask("IPv4 gateway, if any", ""); err != nil {
+		return iface, err
+	}
+	iface.Nameservers, err = p.ask("Nameservers, comma-separated, if any", "")
+	return iface, err
+}
+
+// runTUI prompts for a config on in and out and prints its YAML on out,
+// logging any warnings like -input does
+func runTUI(in io.Reader, out io.Writer) error {
+	formData, err := promptFormData(in, out)
+	if err != nil {
+		return err
+	}
+	config, err := generateNetplanConfig(formData)
+	if err != nil {
+		return err
+	}
+	for _, warning := range config.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	fmt.Fprintln(out)
+	_, err = io.WriteString(out, renderYAML(config, formData))
+	return err
+}
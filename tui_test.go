@@ -0,0 +1,59 @@
+/*
+Tests for interactive generation on the terminal
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptFormData(t *testing.T) {
+	// An empty answer takes the default; an invalid choice and a missing
+	// required answer are asked again
+	answers := []string{
+		"",                             // renderer: networkd
+		"wifi", "ethernet", "", "eth0", // type, retried; name, retried
+		"static", "192.168.1.10/24", "192.168.1.1", "8.8.8.8",
+		"y",
+		"bond", "bond0", "eth1,eth2", "", // members; dhcp
+		"", // no more interfaces
+	}
+	var out strings.Builder
+	formData, err := promptFormData(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+	if err != nil {
+		t.Fatalf("promptFormData failed: %v", err)
+	}
+
+	expected := FormData{
+		Renderer: "networkd",
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", UseStatic: true, Addresses: "192.168.1.10/24", Gateway4: "192.168.1.1", Nameservers: "8.8.8.8"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1,eth2"},
+		},
+	}
+	if !reflect.DeepEqual(formData, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, formData)
+	}
+	for _, prompt := range []string{"Please answer one of ethernet, bond, bridge, vlan, dummy", "An answer is required", "Interface name: "} {
+		if !strings.Contains(out.String(), prompt) {
+			t.Errorf("Expected %q in the prompts:\n%s", prompt, out.String())
+		}
+	}
+
+	// Running out of answers stops rather than prompting forever
+	if _, err := promptFormData(strings.NewReader("networkd\nethernet\n"), io.Discard); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
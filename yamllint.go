@@ -0,0 +1,65 @@
+/*
+Lint checks guarding the hand-written YAML output
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lintYAML checks YAML written by writeConfigYAML for what netplan rejects
+// or a reviewer would flag: tab characters, trailing whitespace and
+// indentation that isn't in steps of indentWidth. A line may only be
+// indented one step deeper than the line before it; list items are written
+// with their keys one step after the dash, so this holds for them too.
+// Comment lines are only checked for tabs and trailing whitespace, as
+// disabled interfaces are written as comments with their own indentation.
+func lintYAML(yaml string, indentWidth int) []string {
+	var violations []string
+	previous := 0
+	for i, line := range strings.Split(strings.TrimSuffix(yaml, "\n"), "\n") {
+		n := i + 1
+		if strings.Contains(line, "\t") {
+			violations = append(violations, fmt.Sprintf("line %d: tab character", n))
+		}
+		if strings.TrimRight(line, " \t") != line {
+			violations = append(violations, fmt.Sprintf("line %d: trailing whitespace", n))
+		}
+
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		indent := len(line) - len(content)
+		switch {
+		case indent%indentWidth != 0:
+			violations = append(violations, fmt.Sprintf("line %d: indented by %d spaces, not a multiple of %d", n, indent, indentWidth))
+		case indent > previous+indentWidth:
+			violations = append(violations, fmt.Sprintf("line %d: indented by %d spaces, more than one level deeper than the line before", n, indent))
+		}
+		previous = indent
+	}
+	return violations
+}
+
+// checkOutputLint lints the YAML the config is written as, so a regression
+// in the writer is reported instead of producing YAML netplan may reject
+func checkOutputLint(config *NetplanConfig) error {
+	indentWidth := config.IndentWidth
+	if indentWidth == 0 {
+		indentWidth = 2
+	}
+	if violations := lintYAML(configToYAML(config), indentWidth); len(violations) > 0 {
+		return fmt.Errorf("internal error: the generated YAML failed lint checks: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
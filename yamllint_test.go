@@ -0,0 +1,75 @@
+/*
+Tests for the lint checks on the YAML output
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintYAML(t *testing.T) {
+	formData := FormData{
+		Interfaces: []InterfaceDefinition{
+			{Type: "ethernet", Name: "eth0", Description: "uplink", UseStatic: true, Addresses: "192.168.1.10/24, 2001:db8::10/64", Gateway4: "192.168.1.1", Nameservers: "8.8.8.8", SearchDomains: "example.com", Routes: "10.0.0.0/8 192.168.1.254 100"},
+			{Type: "ethernet", Name: "eth1"},
+			{Type: "ethernet", Name: "eth2"},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth1, eth2", UseStatic: true, Addresses: "10.1.0.2/24", Gateway4: "10.1.0.1"},
+			{Type: "vlan", Name: "vlan100", VLANID: "100", VLANLink: "bond0", UseStatic: true, Addresses: "10.100.0.2/24", Gateway4: "10.100.0.1"},
+			{Type: "ethernet", Name: "eth3", Disabled: true},
+		},
+		Renderer:        "networkd",
+		CommentDisabled: true,
+	}
+	for _, indentWidth := range []int{2, 4} {
+		formData.IndentWidth = indentWidth
+		config, err := generateNetplanConfig(formData)
+		if err != nil {
+			t.Fatalf("generateNetplanConfig with indent %d failed: %v", indentWidth, err)
+		}
+		if violations := lintYAML(configToYAML(config), indentWidth); len(violations) > 0 {
+			t.Errorf("Expected no lint violations with indent %d, got %v:\n%s", indentWidth, violations, configToYAML(config))
+		}
+	}
+
+	bad := "network:\n\tversion: 2\n  renderer: networkd \n      ethernets:\n   eth0: {}\n"
+	if violations := lintYAML(bad, 2); len(violations) != 4 {
+		t.Errorf("Expected 4 violations, got %v", violations)
+	}
+}
+
+func TestUnsafeInterfaceNames(t *testing.T) {
+	for _, name := range []string{"eth0\nbogus: true", "eth0: x", "eth0 #c", "eth0:1", "[x]", "'q", "*x", "&a", "!t", `a"b`} {
+		for _, iface := range []InterfaceDefinition{
+			{Type: "ethernet", Name: name},
+			{Type: "bond", Name: "bond0", BondInterfaces: "eth0," + name},
+		} {
+			_, err := generateNetplanConfig(FormData{Interfaces: []InterfaceDefinition{iface}, Renderer: "networkd"})
+			if err == nil || !strings.Contains(err.Error(), "invalid interface name") {
+				t.Errorf("Expected a validation error for %q in %+v, got %v", name, iface, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"eth0", "eth0.100", "br_lan-1"} {
+		config, err := generateNetplanConfig(FormData{Interfaces: []InterfaceDefinition{{Type: "ethernet", Name: name}}, Renderer: "networkd"})
+		if err != nil {
+			t.Fatalf("generateNetplanConfig failed for %q: %v", name, err)
+		}
+		parsed, err := parseNetplanYAML(configToYAML(config))
+		if err != nil {
+			t.Fatalf("parseNetplanYAML failed for %q: %v", name, err)
+		}
+		if !hasKey(parsed.Network.Ethernets, name) {
+			t.Errorf("Expected %q to round-trip, got %v", name, parsed.Network.Ethernets)
+		}
+	}
+}
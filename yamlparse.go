@@ -0,0 +1,457 @@
+/*
+YAML parsing for netplan configurations
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseNetplanYAML parses netplan YAML back into a NetplanConfig. Only the
+// block-style subset written by configToYAML and treeToYAML is supported,
+// plus comments and flow sequences such as "[ipv4, ipv6]".
+//
+// For any form that generateNetplanConfig accepts, parsing the output of
+// configToYAML gives back an equal config. What does not survive is:
+//   - Warnings, which are never written to the YAML
+//   - interface descriptions and commented-out disabled interfaces, which
+//     are only written as comments
+//   - dhcp4: false and dhcp6: false left out by MinimalOutput, which come
+//     back unset
+//   - promiscuous mode, which netplan has no key for; with NetworkManager
+//     it comes back only as its passthrough setting
+//   - the form itself: per-family nameservers, CSV routes, MTU and interval
+//     unit suffixes and gateways converted for the target release all come
+//     back in their generated form
+//   - addresses, gateways, nameservers and interface names, which are
+//     written unquoted, so a value YAML reads as a number or boolean comes
+//     back as its string form (e.g. "1e3" as "1000")
+//   - section renderers for sections without any interfaces, which are
+//     not written
+func parseNetplanYAML(input string) (*NetplanConfig, error) {
+	tree, err := parseYAMLTree(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Section renderers share their mapping with the interface names, so
+	// they are taken out before the sections are decoded as interface maps
+	sectionRenderers := make(map[string]string)
+	if network, ok := tree.Get("network"); ok {
+		if network, ok := network.(*yamlMap); ok {
+			for _, section := range interfaceSections {
+				node, _ := network.Get(section)
+				m, ok := node.(*yamlMap)
+				if !ok {
+					continue
+				}
+				if renderer, ok := m.Get("renderer"); ok {
+					name, ok := renderer.(string)
+					if !ok {
+						return nil, fmt.Errorf("network.%s.renderer: expected a string", section)
+					}
+					sectionRenderers[section] = name
+					m.Delete("renderer")
+				}
+			}
+		}
+	}
+
+	var config NetplanConfig
+	if err := treeToValue(tree, reflect.ValueOf(&config).Elem(), ""); err != nil {
+		return nil, err
+	}
+	if len(sectionRenderers) > 0 {
+		config.Network.SectionRenderers = sectionRenderers
+	}
+	return &config, nil
+}
+
+// yamlKeyPattern matches the plain mapping keys netplan uses, such as
+// interface names and hyphenated option names
+var yamlKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAMLTree parses block-style YAML into a generic YAML tree
+func parseYAMLTree(input string) (*yamlMap, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(input, "\n") {
+		if strings.Contains(raw, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in YAML indentation", i+1)
+		}
+		text := strings.TrimSpace(raw)
+		if text == "" || text == "---" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: indent, text: text})
+	}
+	if len(p.lines) == 0 {
+		return newYAMLMap(), nil
+	}
+
+	if isYAMLListItem(p.lines[0].text) {
+		return nil, fmt.Errorf("line %d: expected a mapping at the top level", p.lines[0].number)
+	}
+	tree, err := p.parseMap(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return tree, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (*yamlMap, error) {
+	m := newYAMLMap()
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isYAMLListItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\", got %q", line.number, line.text)
+		}
+		if _, exists := m.Get(key); exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+
+		value, err := p.parseValue(rest, indent, line)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key, value)
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseList(indent int) ([]interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isYAMLListItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if _, _, ok := splitYAMLKey(item); ok {
+			// "- key: value" starts a mapping whose keys line up with
+			// the first one, so re-read the item as its first line
+			p.lines[p.pos] = yamlLine{
+				number: line.number,
+				indent: line.indent + len(line.text) - len(item),
+				text:   item,
+			}
+			m, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, m)
+			continue
+		}
+
+		p.pos++
+		value, err := p.parseValue(item, indent+1, line)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// parseValue parses the value following a key or dash: the rest of the
+// line if there is one, otherwise the nested block on the next lines
+func (p *yamlParser) parseValue(rest string, indent int, line yamlLine) (interface{}, error) {
+	if rest != "" {
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line.number, err)
+		}
+		return value, nil
+	}
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+	if isYAMLListItem(next.text) && next.indent >= indent {
+		return p.parseList(next.indent)
+	}
+	if next.indent > indent {
+		return p.parseMap(next.indent)
+	}
+	return nil, nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" or "key:" into the key and the rest of
+// the line; ok is false if the text is not a mapping entry
+func splitYAMLKey(text string) (string, string, bool) {
+	key, rest := text, ""
+	if i := strings.Index(text, ": "); i >= 0 {
+		key, rest = text[:i], strings.TrimSpace(text[i+2:])
+	} else if strings.HasSuffix(text, ":") {
+		key = strings.TrimSuffix(text, ":")
+	} else {
+		return "", "", false
+	}
+	if !yamlKeyPattern.MatchString(key) {
+		return "", "", false
+	}
+	return key, rest, true
+}
+
+// parseYAMLScalar parses a scalar or a flow sequence or mapping, e.g.
+// "true", "1500", "\"00:11:22:33:44:55\"", "[ipv4, ipv6]" or "{}"
+func parseYAMLScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := closingQuote(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		if err := checkTrailing(s[end+1:]); err != nil {
+			return nil, err
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(strings.ReplaceAll(s[1:], "''", "\x00\x00"), "'")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		if err := checkTrailing(s[end+2:]); err != nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(s[1:end+1], "''", "'"), nil
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %s", s)
+		}
+		list := []interface{}{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			value, err := parseYAMLScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated flow mapping %s", s)
+		}
+		m := newYAMLMap()
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return m, nil
+		}
+		for _, entry := range strings.Split(inner, ",") {
+			key, rest, ok := splitYAMLKey(strings.TrimSpace(entry))
+			if !ok {
+				return nil, fmt.Errorf("invalid flow mapping entry %q", entry)
+			}
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, value)
+		}
+		return m, nil
+	}
+
+	switch strings.ToLower(s) {
+	case "", "null", "~":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// closingQuote returns the index of the double quote ending the string
+// that starts at s[0], or -1
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func checkTrailing(s string) error {
+	s = strings.TrimSpace(s)
+	if s != "" && !strings.HasPrefix(s, "#") {
+		return fmt.Errorf("unexpected %q after quoted string", s)
+	}
+	return nil
+}
+
+// treeToValue stores a generic YAML tree node into v using the yaml struct
+// tags; it is the inverse of valueToTree. Unknown keys are rejected, as
+// netplan itself does.
+func treeToValue(node interface{}, v reflect.Value, path string) error {
+	if node == nil {
+		return nil
+	}
+	at := func() string {
+		if path == "" {
+			return "document"
+		}
+		return path
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		value := reflect.New(v.Type().Elem())
+		if err := treeToValue(node, value.Elem(), path); err != nil {
+			return err
+		}
+		v.Set(value)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(node))
+		return nil
+	case reflect.Struct:
+		m, ok := node.(*yamlMap)
+		if !ok {
+			return fmt.Errorf("%s: expected a mapping", at())
+		}
+		fields := make(map[string]int)
+		for i := 0; i < v.NumField(); i++ {
+			if name, _ := parseYAMLTag(v.Type().Field(i)); name != "" {
+				fields[name] = i
+			}
+		}
+		for _, key := range m.Keys() {
+			i, known := fields[key]
+			if !known {
+				return fmt.Errorf("%s: unknown key %q", at(), key)
+			}
+			value, _ := m.Get(key)
+			if err := treeToValue(value, v.Field(i), joinYAMLPath(path, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		m, ok := node.(*yamlMap)
+		if !ok {
+			return fmt.Errorf("%s: expected a mapping", at())
+		}
+		result := reflect.MakeMap(v.Type())
+		for _, key := range m.Keys() {
+			value, _ := m.Get(key)
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := treeToValue(value, elem, joinYAMLPath(path, key)); err != nil {
+				return err
+			}
+			result.SetMapIndex(reflect.ValueOf(key), elem)
+		}
+		v.Set(result)
+		return nil
+	case reflect.Slice:
+		list, ok := node.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a list", at())
+		}
+		result := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := treeToValue(item, result.Index(i), fmt.Sprintf("%s[%d]", at(), i)); err != nil {
+				return err
+			}
+		}
+		v.Set(result)
+		return nil
+	case reflect.String:
+		switch value := node.(type) {
+		case string:
+			v.SetString(value)
+		case *yamlMap, []interface{}:
+			return fmt.Errorf("%s: expected a string", at())
+		default:
+			v.SetString(formatYAMLScalar(value))
+		}
+		return nil
+	case reflect.Bool:
+		b, ok := node.(bool)
+		if !ok {
+			return fmt.Errorf("%s: expected true or false", at())
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := node.(int)
+		if !ok {
+			return fmt.Errorf("%s: expected an integer", at())
+		}
+		v.SetInt(int64(n))
+		return nil
+	}
+	return fmt.Errorf("%s: unsupported field type %s", at(), v.Type())
+}
+
+func joinYAMLPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,102 @@
+/*
+Tests for YAML parsing and generate/parse round trips
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomInterface picks one value for each form field from a small pool,
+// so that every combination of set and unset fields gets exercised
+func randomInterface(rng *rand.Rand, ifaceType, name string) InterfaceDefinition {
+	pick := func(values ...string) string {
+		return values[rng.Intn(len(values))]
+	}
+	iface := InterfaceDefinition{
+		Type:           ifaceType,
+		Name:           name,
+		UseStatic:      rng.Intn(2) == 0,
+		IPv4Only:       rng.Intn(3) == 0,
+		Optional:       rng.Intn(3) == 0,
+		Critical:       rng.Intn(4) == 0,
+		MTU:            pick("", "1500", "9000b"),
+		LinkLocal:      pick("", "ipv4", "ipv6, ipv4"),
+		Nameservers:    pick("", "1.1.1.1", "8.8.8.8, 2001:4860:4860::8888"),
+		Routes:         pick("", "10.0.0.0/8 192.168.1.254", "default 10.255.255.1 on-link\n172.16.0.0/12 192.168.1.253 50"),
+		RoutesCSV:      pick("", "to,via,metric,table\n10.1.0.0/16,192.168.1.1,10,100"),
+		DHCP4Overrides: pick("", "use-dns=false", "route-metric=100, hostname=web-1, use-ntp=true", "hostname=1.5", "hostname=yes"),
+		DHCP6Overrides: pick("", "use-domains=true"),
+	}
+	if rng.Intn(2) == 0 {
+		acceptRA := rng.Intn(2) == 0
+		iface.AcceptRA = &acceptRA
+	}
+	if iface.UseStatic {
+		iface.Addresses = pick("192.168.1.10/24", "192.168.1.10/24, 2001:db8::10/64")
+		iface.Gateway4 = pick("", "192.168.1.1")
+		iface.Gateway6 = pick("", "2001:db8::1")
+	}
+	if ifaceType == "ethernet" {
+		iface.MACAddress = pick("", "00:11:22:33:44:55", "random", "stable", "preserve")
+	}
+	return iface
+}
+
+func TestGenerateParseRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		formData := FormData{
+			Renderer:      []string{"networkd", "NetworkManager"}[rng.Intn(2)],
+			TargetRelease: []string{"", "20.04", "24.04"}[rng.Intn(3)],
+		}
+		formData.Interfaces = append(formData.Interfaces, randomInterface(rng, "ethernet", "eth0"))
+		if rng.Intn(2) == 0 {
+			bond := randomInterface(rng, "bond", "bond0")
+			bond.BondInterfaces = "eth1,eth2"
+			bond.BondMode = []string{"active-backup", "802.3ad", "balance-rr"}[rng.Intn(3)]
+			bond.BondMIIMonitor = []string{"", "100", "1s"}[rng.Intn(3)]
+			bond.BondMinLinks = []string{"", "1"}[rng.Intn(2)]
+			formData.Interfaces = append(formData.Interfaces, bond)
+		}
+		if rng.Intn(2) == 0 {
+			bridge := randomInterface(rng, "bridge", "br0")
+			bridge.BridgeInterfaces = []string{"eth3", "eth3,eth4"}[rng.Intn(2)]
+			bridge.AllowMemberIPv6 = rng.Intn(2) == 0
+			formData.Interfaces = append(formData.Interfaces, bridge)
+		}
+
+		t.Run(fmt.Sprintf("case%d", i), func(t *testing.T) {
+			config, err := generateNetplanConfig(formData)
+			if err != nil {
+				t.Skipf("form rejected: %v", err)
+			}
+			yaml := configToYAML(config)
+
+			parsed, err := parseNetplanYAML(yaml)
+			if err != nil {
+				t.Fatalf("parseNetplanYAML failed: %v\n%s", err, yaml)
+			}
+
+			config.Warnings = nil
+			if !reflect.DeepEqual(config, parsed) {
+				t.Errorf("Round trip changed the config:\ngenerated %+v\nparsed    %+v\nYAML:\n%s", config.Network, parsed.Network, yaml)
+			}
+			if again := configToYAML(parsed); again != yaml {
+				t.Errorf("Round trip changed the YAML:\n%s\n---\n%s", yaml, again)
+			}
+		})
+	}
+}
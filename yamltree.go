@@ -0,0 +1,256 @@
+/*
+Generic YAML tree for netplan configurations
+
+Copyright (C) 2025 Michael Tinsay
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// yamlMap is a YAML mapping that remembers the order its keys were added
+// in. Values are *yamlMap, []interface{}, string, int, float64 or bool.
+type yamlMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newYAMLMap() *yamlMap {
+	return &yamlMap{values: make(map[string]interface{})}
+}
+
+func (m *yamlMap) Get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *yamlMap) Set(key string, value interface{}) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *yamlMap) Delete(key string) {
+	if _, exists := m.values[key]; !exists {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *yamlMap) Keys() []string {
+	return m.keys
+}
+
+// configToTree converts a config into a generic YAML tree using the yaml
+// struct tags, so struct field order becomes key order. Map keys, such as
+// interface names, are sorted, after any section renderer.
+func configToTree(config *NetplanConfig) *yamlMap {
+	tree := valueToTree(reflect.ValueOf(*config)).(*yamlMap)
+
+	node, _ := tree.Get("network")
+	network := node.(*yamlMap)
+	for section, renderer := range config.Network.SectionRenderers {
+		node, ok := network.Get(section)
+		if !ok {
+			continue
+		}
+		interfaces := node.(*yamlMap)
+		m := newYAMLMap()
+		m.Set("renderer", renderer)
+		for _, key := range interfaces.Keys() {
+			value, _ := interfaces.Get(key)
+			m.Set(key, value)
+		}
+		network.Set(section, m)
+	}
+	return tree
+}
+
+func valueToTree(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return valueToTree(v.Elem())
+	case reflect.Struct:
+		m := newYAMLMap()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, omitEmpty := parseYAMLTag(field)
+			if name == "" {
+				continue
+			}
+			value := v.Field(i)
+			if omitEmpty && value.IsZero() {
+				continue
+			}
+			if node := valueToTree(value); node != nil {
+				m.Set(name, node)
+			}
+		}
+		return m
+	case reflect.Map:
+		m := newYAMLMap()
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if node := valueToTree(v.MapIndex(reflect.ValueOf(key))); node != nil {
+				m.Set(key, node)
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, valueToTree(v.Index(i)))
+		}
+		return list
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return nil
+}
+
+// parseYAMLTag returns the key name and omitempty flag of a struct field;
+// the name is empty for fields tagged "-" or without a yaml tag
+func parseYAMLTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("yaml")
+	if tag == "" || tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty
+}
+
+// treeToYAML renders a generic YAML tree as block-style YAML
+func treeToYAML(tree *yamlMap) string {
+	var sb strings.Builder
+	writeYAMLMap(&sb, tree, 0)
+	return sb.String()
+}
+
+func writeYAMLMap(sb *strings.Builder, m *yamlMap, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, key := range m.Keys() {
+		value, _ := m.Get(key)
+		writeYAMLEntry(sb, pad+key+":", value, indent)
+	}
+}
+
+// writeYAMLEntry writes "prefix value" for scalars, or prefix followed by
+// the nested block for maps and lists
+func writeYAMLEntry(sb *strings.Builder, prefix string, value interface{}, indent int) {
+	switch v := value.(type) {
+	case *yamlMap:
+		if len(v.Keys()) == 0 {
+			sb.WriteString(prefix + " {}\n")
+			return
+		}
+		sb.WriteString(prefix + "\n")
+		writeYAMLMap(sb, v, indent+1)
+	case []interface{}:
+		if len(v) == 0 {
+			sb.WriteString(prefix + " []\n")
+			return
+		}
+		sb.WriteString(prefix + "\n")
+		writeYAMLList(sb, v, indent+1)
+	default:
+		sb.WriteString(prefix + " " + formatYAMLScalar(v) + "\n")
+	}
+}
+
+func writeYAMLList(sb *strings.Builder, list []interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, item := range list {
+		m, ok := item.(*yamlMap)
+		if !ok || len(m.Keys()) == 0 {
+			writeYAMLEntry(sb, pad+"-", item, indent)
+			continue
+		}
+		// The first key of a mapping shares the line with the dash
+		for i, key := range m.Keys() {
+			value, _ := m.Get(key)
+			if i == 0 {
+				writeYAMLEntry(sb, pad+"- "+key+":", value, indent+1)
+			} else {
+				writeYAMLEntry(sb, pad+"  "+key+":", value, indent+1)
+			}
+		}
+	}
+}
+
+// formatYAMLScalar renders a scalar, quoting strings a YAML parser would
+// otherwise read as something else
+func formatYAMLScalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if needsYAMLQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func needsYAMLQuotes(s string) bool {
+	if s == "" || macAddressPattern.MatchString(s) {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "!&*?{}[],#|>@`\"'%") || strings.HasPrefix(s, "- ") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		s != strings.TrimSpace(s)
+}